@@ -0,0 +1,429 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	l "github.com/KYVENetwork/KYVE-DLT/loader"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	concurrency   int
+	outDir        string
+	bundleId      int64
+	inspectLimit  int
+	inspectItem   string
+	inspectFormat string
+)
+
+func init() {
+	bundlesCmd.PersistentFlags().StringVar(&cfgPath, "config", "", "set custom config path")
+
+	bundlesDownloadCmd.Flags().StringVarP(&sourceName, "source", "s", "", "name of the source to download")
+	if err := bundlesDownloadCmd.MarkFlagRequired("source"); err != nil {
+		panic(fmt.Errorf("flag 'source' should be required: %w", err))
+	}
+	bundlesDownloadCmd.Flags().StringVarP(&outDir, "out", "o", "", "directory the bundles are stored in")
+	if err := bundlesDownloadCmd.MarkFlagRequired("out"); err != nil {
+		panic(fmt.Errorf("flag 'out' should be required: %w", err))
+	}
+	bundlesDownloadCmd.Flags().Int64Var(&fromBundleId, "from-bundle-id", 0, "ID of first bundle to download (inclusive)")
+	bundlesDownloadCmd.Flags().Int64Var(&toBundleId, "to-bundle-id", 0, "ID of last bundle to download (inclusive)")
+	bundlesDownloadCmd.Flags().IntVar(&concurrency, "concurrency", 4, "number of parallel downloads")
+
+	bundlesInspectCmd.Flags().StringVarP(&sourceName, "source", "s", "", "name of the source of the bundle")
+	if err := bundlesInspectCmd.MarkFlagRequired("source"); err != nil {
+		panic(fmt.Errorf("flag 'source' should be required: %w", err))
+	}
+	bundlesInspectCmd.Flags().Int64Var(&bundleId, "bundle-id", 0, "ID of the bundle to inspect")
+	if err := bundlesInspectCmd.MarkFlagRequired("bundle-id"); err != nil {
+		panic(fmt.Errorf("flag 'bundle-id' should be required: %w", err))
+	}
+	bundlesInspectCmd.Flags().IntVar(&inspectLimit, "limit", 10, "maximum number of items and rows to print, 0 prints all")
+	bundlesInspectCmd.Flags().StringVar(&inspectItem, "item", "", "only print the item with this key and its rows")
+	bundlesInspectCmd.Flags().StringVar(&inspectFormat, "format", "table", "output format of the rows: table, csv or json")
+
+	bundlesCmd.AddCommand(bundlesDownloadCmd)
+	bundlesCmd.AddCommand(bundlesInspectCmd)
+
+	rootCmd.AddCommand(bundlesCmd)
+}
+
+var bundlesCmd = &cobra.Command{
+	Use:     "bundles",
+	Short:   "Download or inspect bundles of a source",
+	Aliases: []string{"b"},
+}
+
+var bundlesDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download and verify a range of bundles into a local archive",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := utils.LoadConfig(utils.GetConfigPath(cfgPath))
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to load config")
+			return
+		}
+
+		if err := l.SetupStorage(config); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to set up storage")
+			return
+		}
+
+		fetcher, archive, err := newSourceFromFlags(cmd, config)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create source")
+			return
+		}
+		if archive != nil {
+			defer archive.Close()
+		}
+
+		writer, err := collector.NewArchiveWriter(outDir)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create output directory")
+			return
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if err := forEachPage(ctx, fetcher, fromBundleId, func(bundles []collector.Bundle) error {
+			if err := downloadBundles(ctx, writer, archive, bundles, max(concurrency, 1)); err != nil {
+				return err
+			}
+			if err := writer.WritePage(bundles); err != nil {
+				return err
+			}
+			logger.Info().
+				Str("from", bundles[0].Id).
+				Str("to", bundles[len(bundles)-1].Id).
+				Int("amount", len(bundles)).
+				Msg("downloaded")
+			return nil
+		}); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to download bundles")
+			return
+		}
+
+		logger.Info().Str("source", sourceName).Str("out", outDir).Msg("finished download")
+	},
+}
+
+var bundlesInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Download and decode a single bundle and preview the rows of the schema",
+	Run: func(cmd *cobra.Command, args []string) {
+		if inspectFormat != "table" && inspectFormat != "csv" && inspectFormat != "json" {
+			logger.Error().Str("format", inspectFormat).Msg("unknown format, expected table, csv or json")
+			return
+		}
+
+		config, err := utils.LoadConfig(utils.GetConfigPath(cfgPath))
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to load config")
+			return
+		}
+
+		if err := l.SetupStorage(config); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to set up storage")
+			return
+		}
+
+		source, err := utils.GetSourceDetails(config, sourceName)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to find source")
+			return
+		}
+
+		sourceConfig, err := l.GetSourceConfig(source, bundleId, math.MaxInt64)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create source")
+			return
+		}
+		if sourceConfig.Archive != nil {
+			defer sourceConfig.Archive.Close()
+		}
+
+		fetcher, err := collector.NewSource(sourceConfig)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create source")
+			return
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		bundle, err := fetcher.Bundle(ctx, bundleId)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to fetch bundle")
+			return
+		}
+
+		schemaName, sourceSchema, err := l.GetSourceSchema(source)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create schema")
+			return
+		}
+
+		extra := schema.ExtraData{
+			Name:        "inspect",
+			ExtractedAt: time.Now().UTC(),
+			Archive:     sourceConfig.Archive,
+		}
+		if inspectItem != "" {
+			extra.KeyRange = collector.KeyRange{FromKey: inspectItem, ToKey: inspectItem}
+		}
+
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Bundle", bundle.Id)
+		fmt.Printf("\033[36m%-18s\033[0m %s - %s\n", "Keys", bundle.FromKey, bundle.ToKey)
+		fmt.Printf("\033[36m%-18s\033[0m %s - %s\n", "Indices", bundle.FromIndex, bundle.ToIndex)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Summary", bundle.BundleSummary)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Storage ID", bundle.StorageId)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Storage Provider", bundle.StorageProviderId)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Compression", bundle.CompressionId)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Data Hash", bundle.DataHash)
+		fmt.Printf("\033[36m%-18s\033[0m %s\n", "Uploader", bundle.Uploader)
+		fmt.Printf("\033[36m%-18s\033[0m %s (height %s)\n", "Finalized At", bundle.FinalizedAt.Timestamp.UTC().Format(time.RFC3339), bundle.FinalizedAt.Height)
+
+		// The items are printed while the bundle is converted, only the printed rows are kept
+		printed := 0
+		fmt.Println("\n\033[36mItems\033[0m")
+		extra.OnItem = func(item schema.BaseItem) error {
+			if inspectLimit > 0 && printed >= inspectLimit {
+				return nil
+			}
+			printed++
+			line, err := json.Marshal(item)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(line))
+			return nil
+		}
+		rows := make(map[string][]schema.DataRow)
+		totals := make(map[string]int)
+		result, err := sourceSchema.DownloadAndConvertBundle(*bundle, extra, schema.RowSinkFunc(func(row schema.DataRow) error {
+			totals[row.TableName()]++
+			if inspectLimit <= 0 || len(rows[row.TableName()]) < inspectLimit {
+				rows[row.TableName()] = append(rows[row.TableName()], row)
+			}
+			return nil
+		}))
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to convert bundle")
+			return
+		}
+		fmt.Printf("\033[36m%-18s\033[0m %d (keys %s - %s)\n", "Decoded Items", result.Items.Count, result.Items.FirstKey, result.Items.LastKey)
+		fmt.Printf("\033[36m%-18s\033[0m %s compressed, %s uncompressed\n", "Size", formatBytes(result.CompressedSize), formatBytes(result.UncompressedSize))
+
+		for _, table := range sourceSchema.Tables() {
+			tableRows := rows[table.Name]
+			fmt.Printf("\n\033[36mRows (%s, %d of %d)\033[0m\n", table.FullName(schemaName), len(tableRows), totals[table.Name])
+			if err := printRows(os.Stdout, table.ColumnNames(), tableRows, inspectFormat); err != nil {
+				logger.Error().Str("err", err.Error()).Msg("failed to print rows")
+				return
+			}
+		}
+	},
+}
+
+// printRows writes the rows as table, csv or one JSON object per row.
+func printRows(out io.Writer, columns []string, rows []schema.DataRow, format string) error {
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(out)
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := writer.Write(schema.CSVLine(row)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	case "json":
+		// Objects are written by hand to keep the order of the columns, the values keep their types
+		for _, row := range rows {
+			values := row.Values()
+			fields := make([]string, 0, len(columns))
+			for i, column := range columns {
+				if i >= len(values) {
+					break
+				}
+				name, _ := json.Marshal(column)
+				value, err := json.Marshal(values[i])
+				if err != nil {
+					return err
+				}
+				fields = append(fields, string(name)+":"+string(value))
+			}
+			if _, err := fmt.Fprintf(out, "{%s}\n", strings.Join(fields, ",")); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		// Long values like blocks are truncated to keep the table readable
+		const maxWidth = 48
+		writer := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(writer, strings.Join(columns, "\t"))
+		for _, row := range rows {
+			values := schema.CSVLine(row)
+			for i, value := range values {
+				if runes := []rune(value); len(runes) > maxWidth {
+					values[i] = string(runes[:maxWidth-3]) + "..."
+				}
+			}
+			_, _ = fmt.Fprintln(writer, strings.Join(values, "\t"))
+		}
+		return writer.Flush()
+	}
+}
+
+// downloadBundles stores the verified data of all bundles which are not stored yet.
+func downloadBundles(ctx context.Context, writer *collector.ArchiveWriter, archive *collector.Archive, bundles []collector.Bundle, concurrency int) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+
+	semaphore := make(chan struct{}, concurrency)
+bundles:
+	for _, bundle := range bundles {
+		// Skip bundles of a previous run
+		if data, err := writer.ReadBlob(bundle.StorageId); err == nil && fmt.Sprintf("%x", sha256.Sum256(data)) == bundle.DataHash {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			break bundles
+		case semaphore <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(bundle collector.Bundle) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			err := utils.TryWithExponentialBackoffContext(ctx, func() error {
+				data, err := schema.FetchBundle(bundle, archive)
+				if err != nil {
+					return err
+				}
+				return writer.WriteBlob(bundle.StorageId, data)
+			}, func(err error) {
+				logger.Error().Str("bundle_id", bundle.Id).Str("err", err.Error()).Msg("error, retrying")
+			})
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("bundle %s: %w", bundle.Id, err))
+				mu.Unlock()
+			}
+		}(bundle)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// newSourceFromFlags creates the collector for the --source, --from-bundle-id and --to-bundle-id flags.
+func newSourceFromFlags(cmd *cobra.Command, config *utils.Config) (collector.Source, *collector.Archive, error) {
+	source, err := utils.GetSourceDetails(config, sourceName)
+	if err != nil {
+		return collector.Source{}, nil, err
+	}
+
+	to := int64(math.MaxInt64)
+	if cmd.Flags().Changed("to-bundle-id") {
+		to = toBundleId
+	}
+
+	sourceConfig, err := l.GetSourceConfig(source, fromBundleId, to)
+	if err != nil {
+		return collector.Source{}, nil, err
+	}
+
+	fetcher, err := collector.NewSource(sourceConfig)
+	if err != nil {
+		if sourceConfig.Archive != nil {
+			sourceConfig.Archive.Close()
+		}
+		return collector.Source{}, nil, err
+	}
+	return fetcher, sourceConfig.Archive, nil
+}
+
+// forEachPage calls fn for every page of bundles of the source starting at offset.
+// Failed requests are retried until a fatal error occurs or ctx is done.
+func forEachPage(ctx context.Context, source collector.Source, offset int64, fn func(bundles []collector.Bundle) error) error {
+	it := source.Bundles(offset)
+	for {
+		bundles, err := it.Next(ctx)
+		if errors.Is(err, collector.ErrDone) {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if !collector.IsRetryable(err) {
+				return err
+			}
+			logger.Error().Str("err", err.Error()).Msg("failed to fetch bundles, retry in 5 seconds")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+
+		if len(bundles) == 0 {
+			continue
+		}
+		if err := fn(bundles); err != nil {
+			return err
+		}
+	}
+}
+
+// forEachBundle calls fn for every bundle of the source starting at offset. Failed
+// downloads are retried until a permanent error occurs or ctx is done.
+func forEachBundle(ctx context.Context, source collector.Source, offset int64, fn func(bundle collector.Bundle) error) error {
+	return forEachPage(ctx, source, offset, func(bundles []collector.Bundle) error {
+		for _, bundle := range bundles {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err := utils.TryWithExponentialBackoffContext(ctx, func() error {
+				return fn(bundle)
+			}, func(err error) {
+				logger.Error().Str("bundle_id", bundle.Id).Str("err", err.Error()).Msg("error, retrying")
+			}); err != nil {
+				return fmt.Errorf("bundle %s: %w", bundle.Id, err)
+			}
+		}
+		return nil
+	})
+}
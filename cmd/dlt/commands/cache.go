@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	l "github.com/KYVENetwork/KYVE-DLT/loader"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pruneAll bool
+)
+
+func init() {
+	cacheCmd.PersistentFlags().StringVar(&cfgPath, "config", "", "set custom config path")
+
+	cachePruneCmd.Flags().BoolVar(&pruneAll, "all", false, "remove all bundles from the cache")
+
+	cacheWarmCmd.Flags().StringVarP(&sourceName, "source", "s", "", "name of the source to download")
+	if err := cacheWarmCmd.MarkFlagRequired("source"); err != nil {
+		panic(fmt.Errorf("flag 'source' should be required: %w", err))
+	}
+	cacheWarmCmd.Flags().Int64Var(&fromBundleId, "from-bundle-id", 0, "ID of first bundle to download (inclusive)")
+	cacheWarmCmd.Flags().Int64Var(&toBundleId, "to-bundle-id", 0, "ID of last bundle to download (inclusive)")
+
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheWarmCmd)
+
+	rootCmd.AddCommand(cacheCmd)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local bundle cache",
+}
+
+func loadBundleCache() (*utils.Config, *schema.BundleCache, error) {
+	config, err := utils.LoadConfig(utils.GetConfigPath(cfgPath))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	cache, err := schema.NewBundleCache(utils.GetCacheDir(config), int64(config.Cache.MaxSizeGB)*1024*1024*1024)
+	if err != nil {
+		return nil, nil, err
+	}
+	return config, cache, nil
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove least recently used bundles until the cache fits max_size_gb",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, cache, err := loadBundleCache()
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to open cache")
+			return
+		}
+
+		maxSize := int64(config.Cache.MaxSizeGB) * 1024 * 1024 * 1024
+		if pruneAll {
+			maxSize = 0
+		}
+
+		removed, freed, err := cache.Prune(maxSize)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to prune cache")
+			return
+		}
+
+		logger.Info().Int("bundles", removed).Str("freed", fmt.Sprintf("%d MiB", freed/1024/1024)).Msg("pruned cache")
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, cache, err := loadBundleCache()
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to open cache")
+			return
+		}
+
+		stats, err := cache.Stats()
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to read cache")
+			return
+		}
+
+		fmt.Printf("\033[36m%-10s\033[0m %s\n", "Dir", stats.Dir)
+		fmt.Printf("\033[36m%-10s\033[0m %v\n", "Enabled", config.Cache.Enabled)
+		fmt.Printf("\033[36m%-10s\033[0m %d\n", "Bundles", stats.Entries)
+		fmt.Printf("\033[36m%-10s\033[0m %d MiB\n", "Size", stats.Size/1024/1024)
+		fmt.Printf("\033[36m%-10s\033[0m %d MiB\n", "Max Size", stats.MaxSize/1024/1024)
+	},
+}
+
+var cacheWarmCmd = &cobra.Command{
+	Use:   "warm",
+	Short: "Download bundles of a source into the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, cache, err := loadBundleCache()
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to open cache")
+			return
+		}
+
+		if err := l.SetupStorage(config); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to set up storage")
+			return
+		}
+		schema.SetBundleCache(cache)
+
+		fetcher, archive, err := newSourceFromFlags(cmd, config)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create source")
+			return
+		}
+		if archive != nil {
+			defer archive.Close()
+		}
+
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		if err := forEachBundle(ctx, fetcher, fromBundleId, func(bundle collector.Bundle) error {
+			if _, err := schema.FetchBundle(bundle, nil); err != nil {
+				return err
+			}
+			logger.Info().Str("bundle_id", bundle.Id).Msg("cached")
+			return nil
+		}); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to warm cache")
+			return
+		}
+
+		logger.Info().Str("source", sourceName).Msg("finished warming cache")
+	},
+}
@@ -42,7 +42,7 @@ var initCmd = &cobra.Command{
 
 		sourceName := utils.SelectSource(configNode)
 		if sourceName == "custom" {
-			newSource := utils.CreateSourceEntry()
+			newSource := promptSourceEntry()
 			utils.AddNodeToConfig(configNode, "sources", &newSource)
 			if err := utils.SaveConfigWithComments(configPath, configNode); err != nil {
 				logger.Error().Str("err", err.Error()).Msg("error saving config")
@@ -16,6 +16,14 @@ import (
 
 var (
 	setTo = false
+
+	fromKey  string
+	toKey    string
+	fromTime string
+	toTime   string
+
+	follow       bool
+	pollInterval time.Duration
 )
 
 func init() {
@@ -30,6 +38,21 @@ func init() {
 
 	loadCmd.Flags().Int64Var(&toBundleId, "to-bundle-id", 0, "ID of last bundle to load (inclusive)")
 
+	loadCmd.Flags().StringVar(&fromKey, "from-key", "", "first item key to load, e.g. a block height (inclusive)")
+
+	loadCmd.Flags().StringVar(&toKey, "to-key", "", "last item key to load, e.g. a block height (inclusive)")
+
+	loadCmd.Flags().StringVar(&fromTime, "from-time", "", "load bundles finalized at or after this time (RFC3339 or YYYY-MM-DD)")
+
+	loadCmd.Flags().StringVar(&toTime, "to-time", "", "load bundles finalized at or before this time (RFC3339 or YYYY-MM-DD)")
+
+	loadCmd.Flags().BoolVar(&follow, "follow", false, "keep loading new bundles as they are finalized")
+
+	loadCmd.Flags().DurationVar(&pollInterval, "poll-interval", 30*time.Second, "interval to poll for new bundles in follow mode")
+
+	loadCmd.MarkFlagsMutuallyExclusive("from-bundle-id", "from-key", "from-time")
+	loadCmd.MarkFlagsMutuallyExclusive("to-bundle-id", "to-key", "to-time", "follow")
+
 	loadCmd.Flags().BoolVarP(&force, "force", "f", false, "skips checks if data was already loaded in destination")
 
 	loadCmd.Flags().BoolVarP(&y, "yes", "y", false, "automatically answer yes for all questions")
@@ -49,11 +72,35 @@ var loadCmd = &cobra.Command{
 			setTo = true
 		}
 
-		loader, err := l.SetupLoader(configPath, connectionName, setTo, fromBundleId, toBundleId, force)
+		if follow && pollInterval <= 0 {
+			logger.Error().Dur("poll_interval", pollInterval).Msg("invalid --poll-interval, it has to be positive")
+			return
+		}
+
+		selection := l.RangeSelection{
+			FromKey: fromKey,
+			ToKey:   toKey,
+		}
+		var err error
+		if selection.FromTime, err = parseTime(fromTime); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("invalid --from-time")
+			return
+		}
+		if selection.ToTime, err = parseTime(toTime); err != nil {
+			logger.Error().Str("err", err.Error()).Msg("invalid --to-time")
+			return
+		}
+
+		loader, err := l.SetupLoader(configPath, connectionName, setTo, fromBundleId, toBundleId, force, selection)
 		if err != nil {
 			logger.Error().Str("err", err.Error()).Msg("failed to set up loader")
 			return
 		}
+		defer loader.Close()
+
+		if follow {
+			loader.Follow(pollInterval)
+		}
 
 		startTime := time.Now().Unix()
 
@@ -96,10 +143,26 @@ var loadCmd = &cobra.Command{
 		}
 
 		utils.PrometheusSyncStarted.WithLabelValues(loader.ConnectionName).Inc()
-		loader.Start(ctx, y, false)
+		if err := loader.Start(ctx, y, false); err != nil {
+			utils.PrometheusSyncFailed.WithLabelValues(loader.ConnectionName).Inc()
+			logger.Error().Str("err", err.Error()).Msg("sync failed")
+			loader.Close()
+			os.Exit(1)
+		}
 		utils.PrometheusSyncFinished.WithLabelValues(loader.ConnectionName).Inc()
 		utils.PrometheusLastSyncDuration.WithLabelValues(loader.ConnectionName).Set(float64(time.Now().Unix() - startTime))
 
 		logger.Info().Msg(fmt.Sprintf("Finished sync! Took %d seconds", time.Now().Unix()-startTime))
 	},
 }
+
+// parseTime accepts RFC3339 timestamps and dates, which are interpreted as midnight UTC.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
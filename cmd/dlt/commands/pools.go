@@ -0,0 +1,189 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	poolsEndpoint string
+	recentBundles int64
+)
+
+func init() {
+	poolsCmd.PersistentFlags().StringVarP(&poolsEndpoint, "endpoint", "e", "https://api.kyve.network", "KYVE API endpoint")
+
+	poolsInspectCmd.Flags().Int64VarP(&recentBundles, "bundles", "n", 10, "number of recent bundles to show and to compute statistics from")
+
+	poolsCmd.AddCommand(poolsListCmd)
+	poolsCmd.AddCommand(poolsInspectCmd)
+
+	rootCmd.AddCommand(poolsCmd)
+}
+
+var poolsCmd = &cobra.Command{
+	Use:     "pools",
+	Short:   "List or inspect the pools of a KYVE endpoint",
+	Aliases: []string{"p"},
+}
+
+var poolsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all pools",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+		defer cancel()
+
+		pools, err := collector.ListPools(ctx, []string{poolsEndpoint}, collector.ClientConfig{})
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to list pools")
+			return
+		}
+		if len(pools) == 0 {
+			fmt.Println("No pools found.")
+			return
+		}
+
+		columnOffset := 2
+		maxIdLen, maxNameLen, maxRuntimeLen, maxStatusLen, maxBundlesLen, maxKeyLen := len("ID"), len("Name"), len("Runtime"), len("Status"), len("Bundles"), len("Latest Key")
+		for _, pool := range pools {
+			maxIdLen = max(maxIdLen, len(pool.Id))
+			maxNameLen = max(maxNameLen, len(pool.Name))
+			maxRuntimeLen = max(maxRuntimeLen, len(pool.Runtime))
+			maxStatusLen = max(maxStatusLen, len(poolStatus(pool)))
+			maxBundlesLen = max(maxBundlesLen, len(strconv.FormatInt(pool.TotalBundles, 10)))
+			maxKeyLen = max(maxKeyLen, len(pool.CurrentKey))
+		}
+		maxIdLen += columnOffset
+		maxNameLen += columnOffset
+		maxRuntimeLen += columnOffset
+		maxStatusLen += columnOffset
+		maxBundlesLen += columnOffset
+		maxKeyLen += columnOffset
+
+		fmt.Printf("\033[36m%-*s %-*s %-*s %-*s %-*s %-*s %s\033[0m\n", maxIdLen, "ID", maxNameLen, "Name", maxRuntimeLen, "Runtime", maxStatusLen, "Status", maxBundlesLen, "Bundles", maxKeyLen, "Latest Key", "Proposal Size")
+		for _, pool := range pools {
+			fmt.Printf("%-*s %-*s %-*s %-*s %-*d %-*s %s\n", maxIdLen, pool.Id, maxNameLen, pool.Name, maxRuntimeLen, pool.Runtime, maxStatusLen, poolStatus(pool), maxBundlesLen, pool.TotalBundles, maxKeyLen, pool.CurrentKey, formatBytes(pool.ProposalDataSize))
+		}
+	},
+}
+
+var poolsInspectCmd = &cobra.Command{
+	Use:   "inspect [pool id]",
+	Short: "Show the metadata, bundle rate and recent bundles of a pool",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		poolId, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || poolId < 0 {
+			logger.Error().Str("pool_id", args[0]).Msg("invalid pool id")
+			return
+		}
+		if recentBundles < 1 {
+			logger.Error().Int64("bundles", recentBundles).Msg("--bundles has to be at least 1")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(cmd.Context(), time.Minute)
+		defer cancel()
+
+		source, err := collector.NewSource(collector.SourceConfig{
+			PoolId:     poolId,
+			ToBundleId: math.MaxInt64,
+			Endpoints:  []string{poolsEndpoint},
+		})
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to create source")
+			return
+		}
+
+		pool, err := source.Pool(ctx)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to query pool")
+			return
+		}
+
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "ID", pool.Id)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Name", pool.Name)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Runtime", pool.Runtime)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Recommended Schema", schema.ForRuntime(pool.Runtime))
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Status", poolStatus(*pool))
+		fmt.Printf("\033[36m%-20s\033[0m %d\n", "Bundles", pool.TotalBundles)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Latest Key", pool.CurrentKey)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Upload Interval", time.Duration(pool.UploadInterval)*time.Second)
+		fmt.Printf("\033[36m%-20s\033[0m %d\n", "Storage Provider", pool.StorageProviderId)
+		fmt.Printf("\033[36m%-20s\033[0m %d\n", "Compression", pool.CompressionId)
+		fmt.Printf("\033[36m%-20s\033[0m %s\n", "Proposal Size", formatBytes(pool.ProposalDataSize))
+
+		bundles, err := source.LatestBundles(ctx, recentBundles)
+		if err != nil {
+			logger.Error().Str("err", err.Error()).Msg("failed to fetch recent bundles")
+			return
+		}
+		if len(bundles) == 0 {
+			fmt.Println("\nNo bundles finalized yet.")
+			return
+		}
+
+		// Bundles are returned newest first
+		if len(bundles) > 1 {
+			newest, oldest := bundles[0], bundles[len(bundles)-1]
+			window := newest.FinalizedAt.Timestamp.Sub(oldest.FinalizedAt.Timestamp)
+
+			var items int64
+			for _, bundle := range bundles[:len(bundles)-1] {
+				items += bundleItems(bundle)
+			}
+
+			fmt.Println()
+			fmt.Printf("\033[36m%-20s\033[0m %s\n", "Bundle Interval", (window / time.Duration(len(bundles)-1)).Round(time.Second))
+			if window > 0 {
+				fmt.Printf("\033[36m%-20s\033[0m %.2f\n", "Bundles per Hour", float64(len(bundles)-1)/window.Hours())
+				fmt.Printf("\033[36m%-20s\033[0m %.2f\n", "Items per Hour", float64(items)/window.Hours())
+			}
+			fmt.Printf("\033[36m%-20s\033[0m %.2f\n", "Items per Bundle", float64(items)/float64(len(bundles)-1))
+		}
+
+		fmt.Println()
+		fmt.Printf("\033[36m%-10s %-14s %-14s %-8s %-22s %s\033[0m\n", "ID", "From Key", "To Key", "Items", "Finalized At", "Storage ID")
+		for _, bundle := range bundles {
+			fmt.Printf("%-10s %-14s %-14s %-8d %-22s %s\n", bundle.Id, bundle.FromKey, bundle.ToKey, bundleItems(bundle), bundle.FinalizedAt.Timestamp.UTC().Format(time.DateTime), bundle.StorageId)
+		}
+	},
+}
+
+func poolStatus(pool collector.Pool) string {
+	if pool.Disabled {
+		return "DISABLED"
+	}
+	if pool.Status == "" {
+		return "UNKNOWN"
+	}
+	return strings.TrimPrefix(pool.Status, "POOL_STATUS_")
+}
+
+func bundleItems(bundle collector.Bundle) int64 {
+	fromIndex, _ := strconv.ParseInt(bundle.FromIndex, 10, 64)
+	toIndex, _ := strconv.ParseInt(bundle.ToIndex, 10, 64)
+	return toIndex - fromIndex
+}
+
+func formatBytes(size int64) string {
+	switch {
+	case size >= 1024*1024*1024:
+		return fmt.Sprintf("%.2f GiB", float64(size)/1024/1024/1024)
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.2f MiB", float64(size)/1024/1024)
+	case size >= 1024:
+		return fmt.Sprintf("%.2f KiB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
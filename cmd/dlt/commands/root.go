@@ -13,6 +13,7 @@ var (
 	force          bool
 	fromBundleId   int64
 	logger         = utils.DltLogger("cmd")
+	sourceName     string
 	toBundleId     int64
 	y              bool
 )
@@ -1,7 +1,15 @@
 package commands
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -35,21 +43,7 @@ var sourcesAddCmd = &cobra.Command{
 			return
 		}
 
-		newSource := yaml.Node{
-			Kind: yaml.MappingNode,
-			Content: []*yaml.Node{
-				{Kind: yaml.ScalarNode, Value: "name"},
-				{Kind: yaml.ScalarNode, Value: utils.PromptInput("\033[36mEnter Source name: \033[0m")},
-				{Kind: yaml.ScalarNode, Value: "pool_id"},
-				{Kind: yaml.ScalarNode, Value: utils.PromptPoolId("\033[36mEnter KYVE Pool ID: \033[0m")},
-				{Kind: yaml.ScalarNode, Value: "batch_size"},
-				{Kind: yaml.ScalarNode, Value: utils.PromptBatchSize("\033[36mEnter batch size [default 20]: \033[0m", "20")},
-				{Kind: yaml.ScalarNode, Value: "endpoint"},
-				{Kind: yaml.ScalarNode, Value: utils.PromptInputWithDefault("\033[36mEnter endpoint [default https://api.kyve.network]: \033[0m", "https://api.kyve.network")},
-				{Kind: yaml.ScalarNode, Value: "schema"},
-				{Kind: yaml.ScalarNode, Value: utils.PromptSchemaDropdown("\033[36mSelect schema: \033[0m", []string{"base", "height", "tendermint_preprocessed"})},
-			},
-		}
+		newSource := promptSourceEntry()
 
 		// Find the sources node
 		var sourcesNode *yaml.Node
@@ -161,3 +155,82 @@ var sourcesRemoveCmd = &cobra.Command{
 		}
 	},
 }
+
+// promptSourceEntry asks for a new source, validates the pool ID against
+// the endpoint and recommends a schema for the runtime of the pool.
+func promptSourceEntry() yaml.Node {
+	name := utils.PromptInput("\033[36mEnter Source name: \033[0m")
+	endpoint := utils.PromptInputWithDefault("\033[36mEnter endpoint [default https://api.kyve.network]: \033[0m", "https://api.kyve.network")
+
+	var poolId, recommended string
+	for {
+		poolId = utils.PromptPoolId("\033[36mEnter KYVE Pool ID: \033[0m")
+		pool, err := lookupPool(endpoint, poolId)
+		if errors.Is(err, collector.ErrPoolNotFound) {
+			fmt.Printf("Pool %s does not exist on %s, please try again.\n", poolId, endpoint)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Failed to query pool %s, skipping validation: %v\n", poolId, err)
+			break
+		}
+		recommended = schema.ForRuntime(pool.Runtime)
+		fmt.Printf("Pool %s: %s (runtime: %s, bundles: %d)\n", pool.Id, pool.Name, pool.Runtime, pool.TotalBundles)
+		break
+	}
+
+	batchSize := utils.PromptBatchSize("\033[36mEnter batch size [default 20]: \033[0m", "20")
+
+	// auto is resolved here, so that the config holds the schema the tables were created with
+	schemaNames := schema.Names
+	if recommended != "" {
+		fmt.Printf("Recommended schema: %s\n", recommended)
+		schemaNames = append([]string{schema.Auto}, schema.Names...)
+	}
+	schemaName := utils.PromptSchemaDropdown("\033[36mSelect schema: \033[0m", schemaNames)
+	if schemaName == schema.Auto {
+		schemaName = recommended
+		fmt.Printf("Using schema %s\n", schemaName)
+	}
+
+	return yaml.Node{
+		Kind: yaml.MappingNode,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "name"},
+			{Kind: yaml.ScalarNode, Value: name},
+			{Kind: yaml.ScalarNode, Value: "pool_id"},
+			{Kind: yaml.ScalarNode, Value: poolId},
+			{Kind: yaml.ScalarNode, Value: "batch_size"},
+			{Kind: yaml.ScalarNode, Value: batchSize},
+			{Kind: yaml.ScalarNode, Value: "endpoint"},
+			{Kind: yaml.ScalarNode, Value: endpoint},
+			{Kind: yaml.ScalarNode, Value: "schema"},
+			{Kind: yaml.ScalarNode, Value: schemaName},
+		},
+	}
+}
+
+// lookupPool queries the metadata of a pool from a single endpoint.
+func lookupPool(endpoint, poolId string) (*collector.Pool, error) {
+	id, err := strconv.ParseInt(poolId, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := collector.NewSource(collector.SourceConfig{
+		PoolId:     id,
+		ToBundleId: math.MaxInt64,
+		Endpoints:  []string{endpoint},
+		Client: collector.ClientConfig{
+			Timeout:    10 * time.Second,
+			MaxRetries: 1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return source.Pool(ctx)
+}
@@ -94,20 +94,34 @@ var syncCmd = &cobra.Command{
 			return
 		}
 
+		// Loaders hold open archives of offline sources, they are closed before dlt exits
+		var loaders []*l.Loader
+		closeLoaders := func() {
+			for _, loader := range loaders {
+				loader.Close()
+			}
+		}
+
 		var oneSyncAtATime sync.Mutex
 		// Set up loader and Cron job for each connection
 		for _, c := range connections {
 			// TODO: Improve loader handling to prevent future concurrency issues. Use channel structure instead.
-			loader, err := l.SetupLoader(configPath, c.Name, false, fromBundleId, math.MaxInt64, force)
+			loader, err := l.SetupLoader(configPath, c.Name, false, fromBundleId, math.MaxInt64, force, l.RangeSelection{})
 			if err != nil {
 				logger.Error().Str("connectionName", c.Name).Str("err", err.Error()).Msg("failed to set up loader")
+				closeLoaders()
 				return
 			}
+			loaders = append(loaders, loader)
 
 			logger.Info().Str("connectionName", c.Name).Str("schedule", c.Cron).Msg(fmt.Sprintf("adding connection task to cron scheduler"))
 
+			// A connection which failed with an error that can't be resolved by retrying is not scheduled anymore
+			var job gocron.Job
+			failed := false
+
 			// Cron scheduler setup
-			_, err = cronScheduler.NewJob(
+			job, err = cronScheduler.NewJob(
 				// Register a Cron job for connection with the config's crontab
 				gocron.CronJob(
 					c.Cron, false,
@@ -120,20 +134,36 @@ var syncCmd = &cobra.Command{
 
 						// Lock to ensure that only one loading process is running at a time
 						oneSyncAtATime.Lock()
+						if failed {
+							oneSyncAtATime.Unlock()
+							running = false
+							return
+						}
 
 						logger.Info().Str("connection", loader.ConnectionName).Msg("starting loading process")
 
 						utils.PrometheusSyncStarted.WithLabelValues(loader.ConnectionName).Inc()
-						loader.Start(ctx, true, true)
-						utils.PrometheusSyncFinished.WithLabelValues(loader.ConnectionName).Inc()
-						utils.PrometheusLastSyncDuration.WithLabelValues(loader.ConnectionName).Set(float64(time.Now().Unix() - startTime))
-
-						logger.Info().Msg(fmt.Sprintf("Finished sync for %v! Took %d seconds", loader.ConnectionName, time.Now().Unix()-startTime))
+						if err := loader.Start(ctx, true, true); err != nil {
+							failed = true
+							utils.PrometheusSyncFailed.WithLabelValues(loader.ConnectionName).Inc()
+							logger.Error().Str("connection", loader.ConnectionName).Str("err", err.Error()).
+								Msg("sync failed, removing connection from cron scheduler")
+							if err := cronScheduler.RemoveJob(job.ID()); err != nil {
+								logger.Error().Str("connection", loader.ConnectionName).Str("err", err.Error()).Msg("failed to remove cronjob")
+							}
+							loader.Close()
+						} else {
+							utils.PrometheusSyncFinished.WithLabelValues(loader.ConnectionName).Inc()
+							utils.PrometheusLastSyncDuration.WithLabelValues(loader.ConnectionName).Set(float64(time.Now().Unix() - startTime))
+
+							logger.Info().Msg(fmt.Sprintf("Finished sync for %v! Took %d seconds", loader.ConnectionName, time.Now().Unix()-startTime))
+						}
 						oneSyncAtATime.Unlock()
 						running = false
 
 						// Exit if signal was received during loading process
 						if sigCount >= 1 {
+							closeLoaders()
 							os.Exit(1)
 						}
 					},
@@ -141,6 +171,7 @@ var syncCmd = &cobra.Command{
 			)
 			if err != nil {
 				logger.Error().Str("connectionName", loader.ConnectionName).Str("err", err.Error()).Msg("failed to set up cronjob")
+				closeLoaders()
 				return
 			}
 		}
@@ -164,6 +195,7 @@ var syncCmd = &cobra.Command{
 						os.Exit(1)
 					}
 				} else {
+					closeLoaders()
 					os.Exit(1)
 				}
 			}
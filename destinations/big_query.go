@@ -1,7 +1,6 @@
 package destinations
 
 import (
-	"bytes"
 	"compress/gzip"
 	"context"
 	"encoding/csv"
@@ -12,13 +11,20 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"google.golang.org/api/googleapi"
+	"google.golang.org/api/impersonate"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 	"io"
+	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
 	"cloud.google.com/go/storage"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 type BigQueryConfig struct {
@@ -27,11 +33,41 @@ type BigQueryConfig struct {
 	TableId    string
 	BucketName string
 
+	// Optional credentials, Application Default Credentials are used if empty
+	CredentialsFile           string
+	ImpersonateServiceAccount string
+
+	// Optional custom endpoints, e.g. for a local BigQuery or GCS emulator
+	BigQueryEndpoint      string
+	StorageEndpoint       string
+	WithoutAuthentication bool
+
+	// LoadWriteMethod (default) or StorageWriteMethod
+	WriteMethod string
+
 	BucketWorkerCount   int
 	BigQueryWorkerCount int
+
+	// Staged files are collected until one of the thresholds is reached
+	// and then imported with a single load job.
+	LoadJobMaxFiles int
+	LoadJobMaxBytes int64
+	LoadJobMaxWait  time.Duration
+
+	Partitioning BigQueryPartitioningConfig
 }
 
 func NewBigQuery(config BigQueryConfig) BigQuery {
+	if config.LoadJobMaxFiles <= 0 {
+		config.LoadJobMaxFiles = 1
+	}
+	if config.LoadJobMaxWait <= 0 {
+		config.LoadJobMaxWait = 60 * time.Second
+	}
+	if config.WriteMethod == "" {
+		config.WriteMethod = LoadWriteMethod
+	}
+
 	return BigQuery{
 		config:         config,
 		dataRowChannel: nil,
@@ -40,9 +76,58 @@ func NewBigQuery(config BigQueryConfig) BigQuery {
 }
 
 type BucketBusItem struct {
-	FileName     string
+	// Staged file of every table which received rows
+	FileNames    map[string]string
+	size         int64
 	fromBundleId int64
 	toBundleId   int64
+	sequence     int64
+}
+
+// bigQueryTable is the state of one output table of the schema.
+type bigQueryTable struct {
+	schema.Table
+	id string
+
+	timePartitioning  *bigquery.TimePartitioning
+	rangePartitioning *bigquery.RangePartitioning
+	clustering        *bigquery.Clustering
+	exists            atomic.Bool
+
+	// Only used by the Storage Write API
+	descriptor      protoreflect.MessageDescriptor
+	descriptorProto *descriptorpb.DescriptorProto
+}
+
+// loadJobBatch collects staged files of consecutive ranges which are imported with one load job per table.
+type loadJobBatch struct {
+	items []BucketBusItem
+	size  int64
+	// Position of the batch, the first table is loaded in this order
+	sequence int64
+}
+
+func (l *loadJobBatch) add(item BucketBusItem) {
+	l.items = append(l.items, item)
+	l.size += item.size
+}
+
+func (l *loadJobBatch) isFull(config BigQueryConfig) bool {
+	if len(l.items) >= config.LoadJobMaxFiles {
+		return true
+	}
+	return config.LoadJobMaxBytes > 0 && l.size >= config.LoadJobMaxBytes
+}
+
+// stopTimer stops the timer and drains a tick which fired in the meantime, so that
+// the next batch isn't flushed by the stale tick.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
 }
 
 type BigQuery struct {
@@ -50,27 +135,52 @@ type BigQuery struct {
 	dataRowChannel chan DestinationBusItem
 
 	bucketChannel     chan BucketBusItem
+	batchChannel      chan loadJobBatch
 	bucketWaitGroup   sync.WaitGroup
 	bigQueryWaitGroup sync.WaitGroup
 
+	// Batches are loaded into the first table in the order of their ranges
+	order *commitOrder
+	// Streams of the Storage Write API which wait to be committed in the order of their ranges
+	commits *commitQueue
+
 	schema schema.DataSource
+	// The progress is tracked in the first table, which is loaded last
+	tables []*bigQueryTable
+
+	bigQueryClient *bigquery.Client
+	storageClient  *storage.Client
+	writeClient    *managedwriter.Client
 
 	logger zerolog.Logger
 }
 
-func (b *BigQuery) Close() {}
+func (b *BigQuery) Close() {
+	if b.writeClient != nil {
+		if err := b.writeClient.Close(); err != nil {
+			b.logger.Error().Str("err", err.Error()).Msg("failed to close write client")
+		}
+		b.writeClient = nil
+	}
+	if b.bigQueryClient != nil {
+		if err := b.bigQueryClient.Close(); err != nil {
+			b.logger.Error().Str("err", err.Error()).Msg("failed to close BigQuery client")
+		}
+		b.bigQueryClient = nil
+	}
+	if b.storageClient != nil {
+		if err := b.storageClient.Close(); err != nil {
+			b.logger.Error().Str("err", err.Error()).Msg("failed to close storage client")
+		}
+		b.storageClient = nil
+	}
+}
 
 func (b *BigQuery) GetLatestBundleId() *int64 {
 	ctx := context.Background()
 
-	client, err := bigquery.NewClient(ctx, b.config.ProjectId)
-	if err != nil {
-		b.logger.Error().Msg("failed to create BigQuery client")
-		panic(err)
-	}
-
-	stmt := fmt.Sprintf("SELECT MAX(`bundle_id`) FROM `%s.%s`", b.config.DatasetId, b.config.TableId)
-	query := client.Query(stmt)
+	stmt := fmt.Sprintf("SELECT MAX(`bundle_id`) FROM `%s.%s`", b.config.DatasetId, b.tables[0].id)
+	query := b.bigQueryClient.Query(stmt)
 
 	it, err := query.Read(ctx)
 	if err != nil {
@@ -105,17 +215,83 @@ func (b *BigQuery) Initialize(schema schema.DataSource, destinationChannel chan
 	b.schema = schema
 	b.dataRowChannel = destinationChannel
 	b.bucketChannel = make(chan BucketBusItem, b.config.BucketWorkerCount)
+	b.batchChannel = make(chan loadJobBatch, b.config.BigQueryWorkerCount)
+	b.order = newCommitOrder()
+	b.commits = newCommitQueue()
+
+	if err := b.createClients(context.Background()); err != nil {
+		b.logger.Error().Str("err", err.Error()).Msg("failed to create clients")
+		panic(err)
+	}
+
+	if b.config.WriteMethod != LoadWriteMethod && b.config.WriteMethod != StorageWriteMethod {
+		err := fmt.Errorf("invalid write method %q, expected %s or %s", b.config.WriteMethod, LoadWriteMethod, StorageWriteMethod)
+		b.logger.Error().Str("err", err.Error()).Msg("invalid write method")
+		panic(err)
+	}
+
+	if err := b.config.Partitioning.validateTables(schema.Tables()); err != nil {
+		b.logger.Error().Str("err", err.Error()).Msg("invalid partitioning config")
+		panic(err)
+	}
+	for i, table := range schema.Tables() {
+		partitioning := b.config.Partitioning.tablePartitioning(i, table)
+		timePartitioning, rangePartitioning, err := resolvePartitioning(partitioning, table)
+		if err != nil {
+			b.logger.Error().Str("err", err.Error()).Msg("invalid partitioning config")
+			panic(err)
+		}
+		b.tables = append(b.tables, &bigQueryTable{
+			Table:             table,
+			id:                table.FullName(b.config.TableId),
+			timePartitioning:  timePartitioning,
+			rangePartitioning: rangePartitioning,
+			clustering:        resolveClustering(partitioning, table),
+		})
+	}
+
+	if b.config.WriteMethod == StorageWriteMethod {
+		if err := b.initializeStorageWrite(context.Background()); err != nil {
+			b.logger.Error().Str("err", err.Error()).Msg("failed to initialize storage write")
+			panic(err)
+		}
+	}
 }
 
 func (b *BigQuery) StartProcess(waitGroup *sync.WaitGroup) {
 	waitGroup.Add(1)
 
+	if b.config.WriteMethod == StorageWriteMethod {
+		b.bigQueryWaitGroup.Add(b.config.BigQueryWorkerCount)
+		for i := 1; i <= b.config.BigQueryWorkerCount; i++ {
+			go b.storageWriteWorker(fmt.Sprintf("big_query-%d", i))
+		}
+
+		// Commits the streams of the workers in the order of their ranges
+		committed := make(chan struct{})
+		go func() {
+			b.storageWriteCommitter()
+			close(committed)
+		}()
+
+		go func() {
+			b.bigQueryWaitGroup.Wait()
+			b.commits.close()
+			<-committed
+			waitGroup.Done()
+		}()
+		return
+	}
+
 	// Uploads CSV files to Google Cloud Storage
 	b.bucketWaitGroup.Add(b.config.BucketWorkerCount)
 	for i := 1; i <= b.config.BucketWorkerCount; i++ {
 		go b.bucketWorker(fmt.Sprintf("bucket-%d", i))
 	}
 
+	// Collects the staged files of consecutive ranges into batches
+	go b.batchWorker()
+
 	// Import CSV files from Google Bucket to Table
 	b.bigQueryWaitGroup.Add(b.config.BigQueryWorkerCount)
 	for i := 1; i <= b.config.BigQueryWorkerCount; i++ {
@@ -142,80 +318,282 @@ func (b *BigQuery) bucketWorker(workerId string) {
 			return
 		}
 
-		// Create CSV
-		csvBuffer := new(bytes.Buffer)
-		csvWriter := csv.NewWriter(csvBuffer)
+		files, csvSize, err := b.stageRows(item.Rows)
+		item.Rows.done(err)
+		if item.Rows.aborted() != nil {
+			b.logger.Debug().Str("worker-id", workerId).Int64("fromBundleId", item.FromBundleId).Msg("discarded rows of aborted range")
+			continue
+		}
+		if err != nil {
+			b.logger.Error().
+				Str("worker-id", workerId).
+				Int64("fromBundleId", item.FromBundleId).
+				Int64("toBundleId", item.ToBundleId).
+				Str("err", err.Error()).
+				Msg("failed to stage rows, the range is converted again")
+			continue
+		}
 
-		csvWriter.Write(b.schema.GetCSVSchema())
-		// write items
-		for _, c := range item.Data {
-			err := csvWriter.Write(c.ConvertToCSVLine())
-			if err != nil {
-				panic(err)
+		fileNames := make(map[string]string, len(files))
+		for _, table := range b.tables {
+			file, ok := files[table.Name]
+			if !ok {
+				continue
 			}
+			fileName := fmt.Sprintf("dlt/%s/%s.csv.gz", time.Now().Format("2006-01-02"), uuid.New().String())
+
+			utils.TryWithExponentialBackoff(func() error {
+				return b.uploadCloudBucket(b.config.BucketName, fileName, file)
+			}, func(err error) {
+				b.logger.Error().Str("worker-id", workerId).Str("err", err.Error()).Msg("error, retry in 5 seconds")
+			})
+			_ = os.Remove(file)
+			fileNames[table.Name] = fileName
 		}
-		csvWriter.Flush()
-
-		fileName := fmt.Sprintf("dlt/%s/%s.csv.gz", time.Now().Format("2006-01-02"), uuid.New().String())
-
-		utils.TryWithExponentialBackoff(func() error {
-			return b.uploadCloudBucket(b.config.BucketName, fileName, csvBuffer)
-		}, func(err error) {
-			b.logger.Error().Str("worker-id", workerId).Str("err", err.Error()).Msg("error, retry in 5 seconds")
-		})
 
 		b.bucketChannel <- BucketBusItem{
-			FileName:     fileName,
+			FileNames:    fileNames,
+			size:         csvSize,
 			fromBundleId: item.FromBundleId,
 			toBundleId:   item.ToBundleId,
+			sequence:     item.Sequence,
 		}
 
 		b.logger.Info().
 			Str("worker-id", workerId).
-			Str("fileName", fileName).
+			Int("files", len(fileNames)).
 			Int64("fromBundleId", item.FromBundleId).
 			Int64("toBundleId", item.ToBundleId).
 			Msg(fmt.Sprintf("uploaded"))
 	}
 }
 
+// batchWorker brings the staged files back into the order of their ranges and collects
+// them into batches. A batch only contains consecutive ranges, so that loading the batches
+// in order never leaves a gap below the highest loaded bundle_id, which is used to resume.
+func (b *BigQuery) batchWorker() {
+	defer close(b.batchChannel)
+
+	var batch loadJobBatch
+	var sequence int64
+	flush := func() {
+		if len(batch.items) == 0 {
+			return
+		}
+		batch.sequence = sequence
+		b.batchChannel <- batch
+		batch = loadJobBatch{}
+		sequence++
+	}
+
+	// Items which arrived before the ranges in front of them
+	pending := make(map[int64]BucketBusItem)
+	var next int64
+
+	// The timer only runs while the batch contains at least one file
+	flushTimer := time.NewTimer(b.config.LoadJobMaxWait)
+	stopTimer(flushTimer)
+
+	for {
+		select {
+		case item, ok := <-b.bucketChannel:
+			if !ok {
+				stopTimer(flushTimer)
+				flush()
+				if len(pending) > 0 {
+					b.logger.Error().Int("items", len(pending)).Int64("missing_sequence", next).Msg("staged files were not loaded, a range in front of them is missing")
+				}
+				return
+			}
+
+			pending[item.sequence] = item
+			for {
+				item, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+
+				if len(batch.items) == 0 {
+					flushTimer.Reset(b.config.LoadJobMaxWait)
+				}
+				batch.add(item)
+
+				if batch.isFull(b.config) {
+					stopTimer(flushTimer)
+					flush()
+				}
+			}
+		case <-flushTimer.C:
+			flush()
+		}
+	}
+}
+
 func (b *BigQuery) bigqueryWorker(workerId string) {
 	defer b.bigQueryWaitGroup.Done()
 
-	for {
-		item, ok := <-b.bucketChannel
-		if !ok {
-			b.logger.Info().Str("worker-id", workerId).Msg("Finished")
-			return
+	for batch := range b.batchChannel {
+		b.importBatch(workerId, batch)
+	}
+	b.logger.Info().Str("worker-id", workerId).Msg("Finished")
+}
+
+// importBatch runs one load job per table. The other tables are loaded concurrently with
+// other batches, the first table is loaded last and in the order of the batches, so that
+// the progress tracked in it only includes completely loaded ranges without gaps.
+func (b *BigQuery) importBatch(workerId string, batch loadJobBatch) {
+	for i := len(b.tables) - 1; i >= 0; i-- {
+		table := b.tables[i]
+		if i == 0 {
+			b.order.wait(batch.sequence)
+		}
+
+		uris := make([]string, 0, len(batch.items))
+		for _, item := range batch.items {
+			if fileName, ok := item.FileNames[table.Name]; ok {
+				uris = append(uris, fmt.Sprintf("gs://%s/%s", b.config.BucketName, fileName))
+			}
+		}
+		if len(uris) == 0 {
+			continue
 		}
 
 		utils.TryWithExponentialBackoff(func() error {
-			return b.importCSVExplicitSchema(fmt.Sprintf("gs://%s/%s", b.config.BucketName, item.FileName))
+			return b.importCSVExplicitSchema(table, uris...)
 		}, func(err error) {
-			b.logger.Error().Str("worker-id", workerId).Str("err", err.Error()).Msg("error, retry in 5 seconds")
+			b.logger.Error().Str("worker-id", workerId).Str("table", table.id).Str("err", err.Error()).Msg("error, retry in 5 seconds")
 		})
+	}
+	b.order.done(batch.sequence)
 
+	for _, item := range batch.items {
 		b.logger.Info().
 			Str("worker-id", workerId).
-			Str("fileName", item.FileName).
+			Int("files", len(item.FileNames)).
 			Int64("fromBundleId", item.fromBundleId).
 			Int64("toBundleId", item.toBundleId).
 			Msg("imported")
 	}
+
+	b.logger.Debug().
+		Str("worker-id", workerId).
+		Int("files", len(batch.items)).
+		Int64("size", batch.size).
+		Msg("finished load jobs")
 }
 
-func (b *BigQuery) uploadCloudBucket(bucket, object string, buf io.Reader) error {
-	ctx := context.Background()
-	client, err := storage.NewClient(ctx)
+// stageRows writes the rows into one gzip compressed CSV file per table while the chunks arrive,
+// so that the rows don't have to be held in memory until they are uploaded. It returns the temporary
+// files by table name and the size of the CSV data. The files are removed if an error is returned.
+func (b *BigQuery) stageRows(rows *RowStream) (map[string]string, int64, error) {
+	files := make(map[string]*csvFile, len(b.tables))
+	closeFiles := func() {
+		for _, file := range files {
+			file.close()
+			_ = os.Remove(file.Name())
+		}
+	}
+
+	for chunk := rows.next(); chunk != nil; chunk = rows.next() {
+		for _, table := range b.tables {
+			if len(chunk.rows[table.Name]) == 0 {
+				continue
+			}
+			file, ok := files[table.Name]
+			if !ok {
+				var err error
+				if file, err = newCSVFile(table.ColumnNames()); err != nil {
+					closeFiles()
+					return nil, 0, err
+				}
+				files[table.Name] = file
+			}
+			for _, values := range chunk.rows[table.Name] {
+				if err := file.writer.Write(schema.CSVValues(values)); err != nil {
+					closeFiles()
+					return nil, 0, err
+				}
+			}
+		}
+	}
+	if err := rows.aborted(); err != nil {
+		closeFiles()
+		return nil, 0, err
+	}
+
+	fileNames := make(map[string]string, len(files))
+	var csvSize int64
+	for tableName, file := range files {
+		if err := file.finish(); err != nil {
+			closeFiles()
+			return nil, 0, err
+		}
+		fileNames[tableName] = file.Name()
+		csvSize += file.size
+	}
+	return fileNames, csvSize, nil
+}
+
+// csvFile is a temporary gzip compressed CSV file.
+type csvFile struct {
+	*os.File
+	gzip   *gzip.Writer
+	writer *csv.Writer
+	size   int64
+}
+
+func newCSVFile(columnNames []string) (*csvFile, error) {
+	file, err := os.CreateTemp("", "dlt-*.csv.gz")
 	if err != nil {
-		return fmt.Errorf("storage.NewClient: %w", err)
+		return nil, err
+	}
+	f := &csvFile{File: file, gzip: gzip.NewWriter(file)}
+	f.writer = csv.NewWriter(f)
+	if err := f.writer.Write(columnNames); err != nil {
+		f.close()
+		_ = os.Remove(file.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write compresses the CSV data and counts its size.
+func (f *csvFile) Write(p []byte) (int, error) {
+	n, err := f.gzip.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// finish flushes and closes the file.
+func (f *csvFile) finish() error {
+	f.writer.Flush()
+	if err := f.writer.Error(); err != nil {
+		return err
 	}
-	defer client.Close()
+	if err := f.gzip.Close(); err != nil {
+		return err
+	}
+	return f.File.Close()
+}
+
+func (f *csvFile) close() {
+	_ = f.File.Close()
+}
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*900)
+// uploadCloudBucket uploads a gzip compressed file.
+func (b *BigQuery) uploadCloudBucket(bucket, object string, fileName string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*900)
 	defer cancel()
 
-	o := client.Bucket(bucket).Object(object)
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	o := b.storageClient.Bucket(bucket).Object(object)
 
 	o = o.If(storage.Conditions{DoesNotExist: true})
 
@@ -223,16 +601,9 @@ func (b *BigQuery) uploadCloudBucket(bucket, object string, buf io.Reader) error
 	wc := o.NewWriter(ctx)
 	wc.ContentEncoding = "gzip"
 
-	gzipWriter := gzip.NewWriter(wc)
-	if _, err = io.Copy(gzipWriter, buf); err != nil {
+	if _, err := io.Copy(wc, file); err != nil {
 		return fmt.Errorf("io.Copy: %w", err)
 	}
-	// Flush the gzip writer to ensure all data is written
-	err = gzipWriter.Flush()
-	if err != nil {
-		return fmt.Errorf("gzipFlush: %w", err)
-	}
-	gzipWriter.Close()
 
 	if err := wc.Close(); err != nil {
 		return fmt.Errorf("Writer.Close: %w", err)
@@ -241,21 +612,35 @@ func (b *BigQuery) uploadCloudBucket(bucket, object string, buf io.Reader) error
 	return nil
 }
 
-func (b *BigQuery) importCSVExplicitSchema(bucketFilePath string) error {
+func (b *BigQuery) importCSVExplicitSchema(table *bigQueryTable, bucketFilePaths ...string) error {
 	ctx := context.Background()
-	client, err := bigquery.NewClient(ctx, b.config.ProjectId)
-	if err != nil {
-		return fmt.Errorf("bigquery.NewClient: %v", err)
-	}
-	defer client.Close()
 
-	gcsRef := bigquery.NewGCSReference(bucketFilePath)
+	gcsRef := bigquery.NewGCSReference(bucketFilePaths...)
 	gcsRef.SkipLeadingRows = 1
-	gcsRef.Schema = b.schema.GetBigQuerySchema()
-	loader := client.Dataset(b.config.DatasetId).Table(b.config.TableId).LoaderFrom(gcsRef)
+	gcsRef.Schema = table.BigQueryCSVSchema()
+	ref := b.bigQueryClient.Dataset(b.config.DatasetId).Table(table.id)
+	loader := ref.LoaderFrom(gcsRef)
 	loader.WriteDisposition = bigquery.WriteAppend
-	loader.TimePartitioning = b.schema.GetBigQueryTimePartitioning()
-	loader.Clustering = b.schema.GetBigQueryClustering()
+
+	// Partitioning and clustering can only be specified when the table gets created,
+	// loading into an existing table with a different specification would fail.
+	if !table.exists.Load() {
+		if _, err := ref.Metadata(ctx); err == nil {
+			table.exists.Store(true)
+		} else {
+			var apiErr *googleapi.Error
+			if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+				return fmt.Errorf("failed to get table metadata: %w", err)
+			}
+			loader.TimePartitioning = table.timePartitioning
+			loader.RangePartitioning = table.rangePartitioning
+			loader.Clustering = table.clustering
+		}
+	}
+	// Columns missing in existing tables, e.g. the provenance columns, are added by the load job
+	if table.exists.Load() {
+		loader.SchemaUpdateOptions = []string{"ALLOW_FIELD_ADDITION"}
+	}
 
 	job, err := loader.Run(ctx)
 	if err != nil {
@@ -271,3 +656,62 @@ func (b *BigQuery) importCSVExplicitSchema(bucketFilePath string) error {
 	}
 	return nil
 }
+
+// createClients creates the BigQuery and storage clients which are shared by all workers.
+func (b *BigQuery) createClients(ctx context.Context) error {
+	if b.bigQueryClient != nil && b.storageClient != nil {
+		return nil
+	}
+
+	opts, err := b.clientOptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	bigQueryOpts := append([]option.ClientOption{}, opts...)
+	if b.config.BigQueryEndpoint != "" {
+		bigQueryOpts = append(bigQueryOpts, option.WithEndpoint(b.config.BigQueryEndpoint))
+	}
+	bigQueryClient, err := bigquery.NewClient(ctx, b.config.ProjectId, bigQueryOpts...)
+	if err != nil {
+		return fmt.Errorf("bigquery.NewClient: %w", err)
+	}
+
+	storageOpts := append([]option.ClientOption{}, opts...)
+	if b.config.StorageEndpoint != "" {
+		storageOpts = append(storageOpts, option.WithEndpoint(b.config.StorageEndpoint))
+	}
+	storageClient, err := storage.NewClient(ctx, storageOpts...)
+	if err != nil {
+		_ = bigQueryClient.Close()
+		return fmt.Errorf("storage.NewClient: %w", err)
+	}
+
+	b.bigQueryClient = bigQueryClient
+	b.storageClient = storageClient
+	return nil
+}
+
+func (b *BigQuery) clientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	if b.config.WithoutAuthentication {
+		return []option.ClientOption{option.WithoutAuthentication()}, nil
+	}
+
+	var opts []option.ClientOption
+	if b.config.CredentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(b.config.CredentialsFile))
+	}
+
+	if b.config.ImpersonateServiceAccount != "" {
+		tokenSource, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: b.config.ImpersonateServiceAccount,
+			Scopes:          []string{bigquery.Scope, storage.ScopeReadWrite},
+		}, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to impersonate %s: %w", b.config.ImpersonateServiceAccount, err)
+		}
+		return []option.ClientOption{option.WithTokenSource(tokenSource)}, nil
+	}
+
+	return opts, nil
+}
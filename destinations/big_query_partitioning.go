@@ -0,0 +1,107 @@
+package destinations
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+)
+
+type BigQueryPartitioningConfig struct {
+	// Field and Type override the partitioning defaults of the schema.
+	// Supported types: HOUR, DAY, MONTH, YEAR, RANGE
+	Field string
+	Type  string
+
+	// Only used for integer-range partitioning
+	RangeStart    int64
+	RangeEnd      int64
+	RangeInterval int64
+
+	ClusteringFields []string
+
+	// Tables overrides the defaults of further tables of the schema, keyed by their name.
+	// The fields above only apply to the first table, which tracks the progress.
+	Tables map[string]BigQueryPartitioningConfig
+}
+
+// tablePartitioning returns the override of the table at the given index of the schema.
+func (c BigQueryPartitioningConfig) tablePartitioning(index int, table schema.Table) BigQueryPartitioningConfig {
+	if override, ok := c.Tables[table.Name]; ok {
+		return override
+	}
+	if index == 0 {
+		return BigQueryPartitioningConfig{
+			Field:            c.Field,
+			Type:             c.Type,
+			RangeStart:       c.RangeStart,
+			RangeEnd:         c.RangeEnd,
+			RangeInterval:    c.RangeInterval,
+			ClusteringFields: c.ClusteringFields,
+		}
+	}
+	return BigQueryPartitioningConfig{}
+}
+
+// validateTables rejects overrides of tables which don't exist in the schema.
+func (c BigQueryPartitioningConfig) validateTables(tables []schema.Table) error {
+	names := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		names[table.Name] = true
+	}
+	for name := range c.Tables {
+		if !names[name] {
+			return fmt.Errorf("partitioning of unknown table %q", name)
+		}
+	}
+	return nil
+}
+
+// resolvePartitioning returns the partitioning of the table. Only one of the
+// return values is set. The override takes precedence over the schema defaults.
+func resolvePartitioning(config BigQueryPartitioningConfig, table schema.Table) (*bigquery.TimePartitioning, *bigquery.RangePartitioning, error) {
+	if config.Field == "" && config.Type == "" {
+		if table.RangePartitioning != nil {
+			return nil, table.RangePartitioning, nil
+		}
+		return table.TimePartitioning, nil, nil
+	}
+
+	if config.Field == "" {
+		return nil, nil, fmt.Errorf("partitioning.field is required if partitioning.type is set")
+	}
+
+	switch strings.ToUpper(config.Type) {
+	case "RANGE":
+		if config.RangeInterval <= 0 || config.RangeEnd <= config.RangeStart {
+			return nil, nil, fmt.Errorf("invalid integer range partitioning: start %d, end %d, interval %d",
+				config.RangeStart, config.RangeEnd, config.RangeInterval)
+		}
+		return nil, &bigquery.RangePartitioning{
+			Field: config.Field,
+			Range: &bigquery.RangePartitioningRange{
+				Start:    config.RangeStart,
+				End:      config.RangeEnd,
+				Interval: config.RangeInterval,
+			},
+		}, nil
+	case "HOUR":
+		return &bigquery.TimePartitioning{Field: config.Field, Type: bigquery.HourPartitioningType}, nil, nil
+	case "", "DAY":
+		return &bigquery.TimePartitioning{Field: config.Field, Type: bigquery.DayPartitioningType}, nil, nil
+	case "MONTH":
+		return &bigquery.TimePartitioning{Field: config.Field, Type: bigquery.MonthPartitioningType}, nil, nil
+	case "YEAR":
+		return &bigquery.TimePartitioning{Field: config.Field, Type: bigquery.YearPartitioningType}, nil, nil
+	default:
+		return nil, nil, fmt.Errorf("partition type not supported: %v", config.Type)
+	}
+}
+
+func resolveClustering(config BigQueryPartitioningConfig, table schema.Table) *bigquery.Clustering {
+	if len(config.ClusteringFields) > 0 {
+		return &bigquery.Clustering{Fields: config.ClusteringFields}
+	}
+	return table.Clustering
+}
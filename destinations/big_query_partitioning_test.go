@@ -0,0 +1,58 @@
+package destinations
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+)
+
+func TestPartitioningOverridePerTable(t *testing.T) {
+	tables := schema.Evm{}.Tables()
+	config := BigQueryPartitioningConfig{
+		Field:            "block_number",
+		Type:             "RANGE",
+		RangeEnd:         100_000_000,
+		RangeInterval:    10_000,
+		ClusteringFields: []string{"miner"},
+		Tables: map[string]BigQueryPartitioningConfig{
+			"logs": {Field: "block_timestamp", Type: "MONTH", ClusteringFields: []string{"address", "block_number"}},
+		},
+	}
+	if err := config.validateTables(tables); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, table := range tables {
+		partitioning := config.tablePartitioning(i, table)
+		timePartitioning, rangePartitioning, err := resolvePartitioning(partitioning, table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		clustering := resolveClustering(partitioning, table)
+
+		switch table.Name {
+		case "blocks":
+			if rangePartitioning == nil || rangePartitioning.Field != "block_number" || clustering.Fields[0] != "miner" {
+				t.Fatalf("blocks: override of the first table not applied: %+v %+v", rangePartitioning, clustering)
+			}
+		case "transactions":
+			if timePartitioning != table.TimePartitioning || clustering != table.Clustering {
+				t.Fatalf("transactions: expected the schema defaults, got %+v %+v", timePartitioning, clustering)
+			}
+		case "logs":
+			if timePartitioning == nil || timePartitioning.Type != bigquery.MonthPartitioningType || clustering.Fields[0] != "address" {
+				t.Fatalf("logs: override by name not applied: %+v %+v", timePartitioning, clustering)
+			}
+		}
+	}
+}
+
+func TestPartitioningOverrideOfUnknownTable(t *testing.T) {
+	config := BigQueryPartitioningConfig{
+		Tables: map[string]BigQueryPartitioningConfig{"log": {Field: "block_timestamp"}},
+	}
+	if err := config.validateTables(schema.Evm{}.Tables()); err == nil {
+		t.Fatal("expected an error for an unknown table")
+	}
+}
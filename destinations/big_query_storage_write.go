@@ -0,0 +1,624 @@
+package destinations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"cloud.google.com/go/bigquery/storage/managedwriter"
+	"cloud.google.com/go/bigquery/storage/managedwriter/adapt"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// LoadWriteMethod stages CSV files in a bucket and imports them with load jobs
+	LoadWriteMethod = "load"
+	// StorageWriteMethod appends the rows as protocol buffers with the BigQuery Storage Write API
+	StorageWriteMethod = "storage_write"
+)
+
+// Requests of the Storage Write API are limited to 10 MB
+const maxAppendRowsBytes = 8 * 1024 * 1024
+
+// storageWriteTypes maps the column types to the protocol buffer types accepted by the Storage Write API.
+var storageWriteTypes = map[schema.ColumnType]descriptorpb.FieldDescriptorProto_Type{
+	schema.StringColumn:     descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	schema.IntegerColumn:    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	schema.BigNumericColumn: descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	schema.TimestampColumn:  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	schema.JSONColumn:       descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	schema.BytesColumn:      descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+}
+
+// rowDescriptor returns a message with one field per column, in the order of the columns.
+func rowDescriptor(table schema.Table) (protoreflect.MessageDescriptor, error) {
+	message := &descriptorpb.DescriptorProto{Name: proto.String("Row")}
+	for i, c := range table.Columns {
+		fieldType, ok := storageWriteTypes[c.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported column type %s of column %s", c.Type, c.Name)
+		}
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if c.Repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		message.Field = append(message.Field, &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(c.Name),
+			Number: proto.Int32(int32(i + 1)),
+			Type:   fieldType.Enum(),
+			Label:  label.Enum(),
+		})
+	}
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("row.proto"),
+		Syntax:      proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{message},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return file.Messages().Get(0), nil
+}
+
+// encodeRow serializes the values of a row, NULL values are left unset.
+func encodeRow(descriptor protoreflect.MessageDescriptor, values []any) ([]byte, error) {
+	fields := descriptor.Fields()
+	if len(values) != fields.Len() {
+		return nil, fmt.Errorf("row has %d values, the table has %d columns", len(values), fields.Len())
+	}
+
+	message := dynamicpb.NewMessage(descriptor)
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		field := fields.Get(i)
+		if elements, ok := value.([]any); ok {
+			list := message.Mutable(field).List()
+			for _, element := range elements {
+				v, err := storageWriteValue(field, element)
+				if err != nil {
+					return nil, fmt.Errorf("column %s: %w", field.Name(), err)
+				}
+				list.Append(v)
+			}
+			continue
+		}
+		v, err := storageWriteValue(field, value)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", field.Name(), err)
+		}
+		message.Set(field, v)
+	}
+	return proto.Marshal(message)
+}
+
+// storageWriteValue converts a value to the kind of the field, dynamicpb panics on values of another kind.
+func storageWriteValue(field protoreflect.FieldDescriptor, value any) (protoreflect.Value, error) {
+	var v protoreflect.Value
+	var kind protoreflect.Kind
+	switch value := value.(type) {
+	case string:
+		v, kind = protoreflect.ValueOfString(value), protoreflect.StringKind
+	case int64:
+		v, kind = protoreflect.ValueOfInt64(value), protoreflect.Int64Kind
+	case *big.Int:
+		v, kind = protoreflect.ValueOfString(value.String()), protoreflect.StringKind
+	case time.Time:
+		v, kind = protoreflect.ValueOfInt64(value.UnixMicro()), protoreflect.Int64Kind
+	case json.RawMessage:
+		v, kind = protoreflect.ValueOfString(string(value)), protoreflect.StringKind
+	case []byte:
+		v, kind = protoreflect.ValueOfBytes(value), protoreflect.BytesKind
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported value type %T", value)
+	}
+	if kind != field.Kind() {
+		return protoreflect.Value{}, fmt.Errorf("value of type %T doesn't match the column type", value)
+	}
+	return v, nil
+}
+
+// initializeStorageWrite creates the tables, which the Storage Write API doesn't do, and the
+// descriptors of their rows.
+func (b *BigQuery) initializeStorageWrite(ctx context.Context) error {
+	opts, err := b.clientOptions(ctx)
+	if err != nil {
+		return err
+	}
+	writeClient, err := managedwriter.NewClient(ctx, b.config.ProjectId, opts...)
+	if err != nil {
+		return fmt.Errorf("managedwriter.NewClient: %w", err)
+	}
+	b.writeClient = writeClient
+
+	for _, table := range b.tables {
+		if err := b.ensureTable(ctx, table); err != nil {
+			return fmt.Errorf("table %s: %w", table.id, err)
+		}
+
+		table.descriptor, err = rowDescriptor(table.Table)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table.id, err)
+		}
+		table.descriptorProto, err = adapt.NormalizeDescriptor(table.descriptor)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", table.id, err)
+		}
+	}
+	return nil
+}
+
+// ensureTable creates the table with its partitioning or adds the missing columns to an existing table.
+func (b *BigQuery) ensureTable(ctx context.Context, table *bigQueryTable) error {
+	ref := b.bigQueryClient.Dataset(b.config.DatasetId).Table(table.id)
+
+	metadata, err := ref.Metadata(ctx)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != 404 {
+			return fmt.Errorf("failed to get table metadata: %w", err)
+		}
+		return ref.Create(ctx, &bigquery.TableMetadata{
+			Schema:            table.BigQuerySchema(),
+			TimePartitioning:  table.timePartitioning,
+			RangePartitioning: table.rangePartitioning,
+			Clustering:        table.clustering,
+		})
+	}
+	table.exists.Store(true)
+
+	existing := make(map[string]bool, len(metadata.Schema))
+	for _, field := range metadata.Schema {
+		existing[field.Name] = true
+	}
+	fields := metadata.Schema
+	for _, field := range table.BigQuerySchema() {
+		if !existing[field.Name] {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == len(metadata.Schema) {
+		return nil
+	}
+	_, err = ref.Update(ctx, bigquery.TableMetadataToUpdate{Schema: fields}, metadata.ETag)
+	return err
+}
+
+func (b *BigQuery) storageWriteWorker(workerId string) {
+	defer b.bigQueryWaitGroup.Done()
+
+	for {
+		item, ok := <-b.dataRowChannel
+		if !ok {
+			b.logger.Debug().Str("worker-id", workerId).Msg("Finished")
+			return
+		}
+
+		streams, count, err := b.writeStreams(workerId, item.Rows)
+		if item.Rows.aborted() != nil {
+			item.Rows.done(err)
+			b.logger.Debug().Str("worker-id", workerId).Int64("fromBundleId", item.FromBundleId).Msg("discarded rows of aborted range")
+			continue
+		}
+		if err != nil {
+			item.Rows.done(err)
+			b.logger.Error().
+				Str("worker-id", workerId).
+				Int64("fromBundleId", item.FromBundleId).
+				Int64("toBundleId", item.ToBundleId).
+				Str("err", err.Error()).
+				Msg("failed to append rows")
+			continue
+		}
+
+		// The committer finishes the rows, so that the loader only counts committed ranges
+		// and fails if they can't be committed
+		b.commits.add(pendingCommit{
+			rows:         item.Rows,
+			streams:      streams,
+			count:        count,
+			fromBundleId: item.FromBundleId,
+			toBundleId:   item.ToBundleId,
+			sequence:     item.Sequence,
+		})
+
+		b.logger.Debug().
+			Str("worker-id", workerId).
+			Int64("fromBundleId", item.FromBundleId).
+			Int64("toBundleId", item.ToBundleId).
+			Int("rows", count).
+			Msg("appended")
+	}
+}
+
+// writeStreams appends the rows of a range to one pending stream per table while the chunks arrive.
+// The rows of pending streams are invisible until the streams are committed, streams of a failed
+// or aborted range are never committed. It returns the finalized streams by table name.
+func (b *BigQuery) writeStreams(workerId string, rows *RowStream) (map[string]string, int, error) {
+	ctx := context.Background()
+
+	streams := make(map[string]*tableStream, len(b.tables))
+	defer func() {
+		for _, stream := range streams {
+			_ = stream.Close()
+		}
+	}()
+	onError := func(table *bigQueryTable) func(err error) {
+		return func(err error) {
+			b.logger.Error().Str("worker-id", workerId).Str("table", table.id).Str("err", err.Error()).Msg("error, retry in 5 seconds")
+		}
+	}
+
+	var count int
+	for chunk := rows.next(); chunk != nil; chunk = rows.next() {
+		for _, table := range b.tables {
+			if len(chunk.rows[table.Name]) == 0 {
+				continue
+			}
+
+			// Encoded once, retries send the same requests
+			requests, err := encodeRequests(table.descriptor, chunk.rows[table.Name])
+			if err != nil {
+				return nil, 0, utils.Permanent(fmt.Errorf("table %s: %w", table.id, err))
+			}
+
+			stream, ok := streams[table.Name]
+			if !ok {
+				err := utils.TryWithExponentialBackoff(func() error {
+					var err error
+					stream, err = b.openStream(ctx, table)
+					return err
+				}, onError(table))
+				if err != nil {
+					return nil, 0, fmt.Errorf("table %s: %w", table.id, err)
+				}
+				streams[table.Name] = stream
+			}
+
+			err = utils.TryWithExponentialBackoff(func() error {
+				return stream.append(ctx, requests)
+			}, onError(table))
+			if err != nil {
+				return nil, 0, fmt.Errorf("table %s: %w", table.id, err)
+			}
+		}
+		count += chunk.count
+	}
+	if err := rows.aborted(); err != nil {
+		return nil, 0, err
+	}
+
+	names := make(map[string]string, len(streams))
+	for _, table := range b.tables {
+		stream, ok := streams[table.Name]
+		if !ok {
+			continue
+		}
+		err := utils.TryWithExponentialBackoff(func() error {
+			_, err := stream.Finalize(ctx)
+			return err
+		}, onError(table))
+		if err != nil {
+			return nil, 0, fmt.Errorf("table %s: %w", table.id, err)
+		}
+		names[table.Name] = stream.StreamName()
+	}
+	return names, count, nil
+}
+
+func (b *BigQuery) openStream(ctx context.Context, table *bigQueryTable) (*tableStream, error) {
+	stream, err := b.writeClient.NewManagedStream(ctx,
+		managedwriter.WithDestinationTable(managedwriter.TableParentFromParts(b.config.ProjectId, b.config.DatasetId, table.id)),
+		managedwriter.WithType(managedwriter.PendingStream),
+		managedwriter.WithSchemaDescriptor(table.descriptorProto),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	return &tableStream{ManagedStream: stream}, nil
+}
+
+// appendRequest is one request of encoded rows, which are written at the offset of the stream.
+type appendRequest struct {
+	rows    [][]byte
+	offset  int64
+	written bool
+}
+
+// encodeRequests serializes the rows and splits them into requests below the size limit.
+// The offsets are relative to the start of the rows.
+func encodeRequests(descriptor protoreflect.MessageDescriptor, rows [][]any) ([]*appendRequest, error) {
+	var requests []*appendRequest
+	var request *appendRequest
+	var requestBytes int
+	for i, values := range rows {
+		data, err := encodeRow(descriptor, values)
+		if err != nil {
+			return nil, err
+		}
+		if request == nil || requestBytes+len(data) > maxAppendRowsBytes {
+			request = &appendRequest{offset: int64(i)}
+			requests = append(requests, request)
+			requestBytes = 0
+		}
+		request.rows = append(request.rows, data)
+		requestBytes += len(data)
+	}
+	return requests, nil
+}
+
+// tableStream is a pending stream of one table.
+type tableStream struct {
+	*managedwriter.ManagedStream
+	// Number of rows which were written, the offset of the next request
+	offset int64
+}
+
+// append writes the requests with explicit offsets and waits until they are written. Requests which
+// failed are sent again at the same offsets by the next call, a request which was written even though
+// it failed is rejected with OFFSET_ALREADY_EXISTS and counts as written.
+func (s *tableStream) append(ctx context.Context, requests []*appendRequest) error {
+	results := make(map[*appendRequest]*managedwriter.AppendResult, len(requests))
+	for _, request := range requests {
+		if request.written {
+			continue
+		}
+		result, err := s.AppendRows(ctx, request.rows, managedwriter.WithOffset(s.offset+request.offset))
+		if err != nil {
+			return err
+		}
+		results[request] = result
+	}
+
+	var appendErr error
+	for _, request := range requests {
+		result, ok := results[request]
+		if !ok {
+			continue
+		}
+		_, err := result.GetResult(ctx)
+		if err == nil || storageErrorCode(err) == storagepb.StorageError_OFFSET_ALREADY_EXISTS {
+			request.written = true
+		} else if appendErr == nil {
+			appendErr = err
+		}
+	}
+	if appendErr != nil {
+		return appendErr
+	}
+
+	for _, request := range requests {
+		s.offset += int64(len(request.rows))
+	}
+	return nil
+}
+
+// storageErrorCode returns the code of the storage error embedded in an error of the Storage Write API.
+func storageErrorCode(err error) storagepb.StorageError_StorageErrorCode {
+	apiErr, ok := apierror.FromError(err)
+	if !ok {
+		return storagepb.StorageError_STORAGE_ERROR_CODE_UNSPECIFIED
+	}
+	storageErr := &storagepb.StorageError{}
+	if err := apiErr.Details().ExtractProtoMessage(storageErr); err != nil {
+		return storagepb.StorageError_STORAGE_ERROR_CODE_UNSPECIFIED
+	}
+	return storageErr.GetCode()
+}
+
+// pendingCommit holds the finalized streams of a range until they are committed.
+type pendingCommit struct {
+	rows *RowStream
+	// Stream name by table name
+	streams      map[string]string
+	count        int
+	fromBundleId int64
+	toBundleId   int64
+	sequence     int64
+}
+
+// commitQueue brings the finalized ranges back into their order. Workers only add to the
+// queue, so that they never wait for the commits.
+type commitQueue struct {
+	mu      sync.Mutex
+	pending map[int64]pendingCommit
+	closed  bool
+	notify  chan struct{}
+}
+
+func newCommitQueue() *commitQueue {
+	return &commitQueue{
+		pending: make(map[int64]pendingCommit),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+func (q *commitQueue) add(commit pendingCommit) {
+	q.mu.Lock()
+	q.pending[commit.sequence] = commit
+	q.mu.Unlock()
+	q.signal()
+}
+
+// close lets take return once the remaining consecutive ranges are taken.
+func (q *commitQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.signal()
+}
+
+func (q *commitQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take waits for the range with the given sequence and returns it with the consecutive ranges behind it.
+// It returns nil if the queue is closed and the range is missing.
+func (q *commitQueue) take(next int64) []pendingCommit {
+	for {
+		q.mu.Lock()
+		var commits []pendingCommit
+		for {
+			commit, ok := q.pending[next]
+			if !ok {
+				break
+			}
+			delete(q.pending, next)
+			commits = append(commits, commit)
+			next++
+		}
+		closed := q.closed
+		q.mu.Unlock()
+
+		if len(commits) > 0 || closed {
+			return commits
+		}
+		<-q.notify
+	}
+}
+
+// remaining returns the number of ranges which weren't taken.
+// remaining removes and returns the ranges which can't be taken, because a range in front of them is missing.
+func (q *commitQueue) remaining() []pendingCommit {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	commits := make([]pendingCommit, 0, len(q.pending))
+	for sequence, commit := range q.pending {
+		commits = append(commits, commit)
+		delete(q.pending, sequence)
+	}
+	return commits
+}
+
+// storageWriteCommitter commits the streams in the order of the ranges and finishes their rows with the
+// result. Once a commit failed, the later ranges are not committed anymore, as the progress would have a gap.
+func (b *BigQuery) storageWriteCommitter() {
+	var next int64
+	var failed error
+	for {
+		commits := b.commits.take(next)
+		if len(commits) == 0 {
+			if remaining := b.commits.remaining(); len(remaining) > 0 {
+				b.logger.Error().Int("items", len(remaining)).Int64("missing_sequence", next).Msg("streams were not committed, a range in front of them is missing")
+				for _, commit := range remaining {
+					commit.rows.done(fmt.Errorf("range %d was not committed, range %d is missing", commit.sequence, next))
+				}
+			}
+			b.logger.Debug().Msg("Finished committing")
+			return
+		}
+		next += int64(len(commits))
+
+		if failed == nil {
+			failed = b.commitRanges(commits)
+		}
+		for _, commit := range commits {
+			commit.rows.done(failed)
+			if failed != nil {
+				continue
+			}
+			b.logger.Info().
+				Int64("fromBundleId", commit.fromBundleId).
+				Int64("toBundleId", commit.toBundleId).
+				Int("rows", commit.count).
+				Msg("committed")
+		}
+	}
+}
+
+// commitRanges commits the streams of consecutive ranges. The streams of the other tables are committed
+// first and the first table last, so that the progress tracked in it only includes completely written
+// ranges without gaps.
+func (b *BigQuery) commitRanges(commits []pendingCommit) error {
+	for i := len(b.tables) - 1; i >= 0; i-- {
+		table := b.tables[i]
+		var names []string
+		for _, commit := range commits {
+			if name, ok := commit.streams[table.Name]; ok {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		err := utils.TryWithExponentialBackoff(func() error {
+			var err error
+			names, err = b.commitStreams(table, names)
+			return err
+		}, func(err error) {
+			b.logger.Error().Str("table", table.id).Str("err", err.Error()).Msg("error, retry in 5 seconds")
+		})
+		if err != nil {
+			b.logger.Error().
+				Str("table", table.id).
+				Int64("fromBundleId", commits[0].fromBundleId).
+				Int64("toBundleId", commits[len(commits)-1].toBundleId).
+				Str("err", err.Error()).
+				Msg("failed to commit streams")
+			return fmt.Errorf("failed to commit streams of table %s: %w", table.id, err)
+		}
+	}
+	return nil
+}
+
+// commitStreams commits the streams of a table atomically. If the commit fails, it returns the streams
+// which still have to be committed: streams which were committed by an earlier attempt are removed,
+// because the whole commit would fail again because of them.
+func (b *BigQuery) commitStreams(table *bigQueryTable, names []string) ([]string, error) {
+	response, err := b.writeClient.BatchCommitWriteStreams(context.Background(), &storagepb.BatchCommitWriteStreamsRequest{
+		Parent:       managedwriter.TableParentFromParts(b.config.ProjectId, b.config.DatasetId, table.id),
+		WriteStreams: names,
+	})
+	if err != nil {
+		return names, err
+	}
+	return uncommittedStreams(names, response.GetStreamErrors())
+}
+
+// uncommittedStreams returns the streams which weren't committed because of the stream errors. Other
+// errors than already committed streams are permanent, committing the streams again fails the same way.
+func uncommittedStreams(names []string, streamErrors []*storagepb.StorageError) ([]string, error) {
+	if len(streamErrors) == 0 {
+		return nil, nil
+	}
+
+	committed := make(map[string]bool)
+	var errs []error
+	for _, streamErr := range streamErrors {
+		if streamErr.GetCode() == storagepb.StorageError_STREAM_ALREADY_COMMITTED {
+			committed[streamErr.GetEntity()] = true
+			continue
+		}
+		errs = append(errs, fmt.Errorf("stream %s: %s: %s", streamErr.GetEntity(), streamErr.GetCode(), streamErr.GetErrorMessage()))
+	}
+	remaining := make([]string, 0, len(names))
+	for _, name := range names {
+		if !committed[name] {
+			remaining = append(remaining, name)
+		}
+	}
+	if len(errs) > 0 {
+		return remaining, utils.Permanent(errors.Join(errs...))
+	}
+	return remaining, fmt.Errorf("%d streams were already committed", len(committed))
+}
@@ -0,0 +1,142 @@
+package destinations
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery/storage/apiv1/storagepb"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestEncodeRequests(t *testing.T) {
+	descriptor, err := rowDescriptor(schema.Table{Columns: []schema.Column{{Name: "value", Type: schema.StringColumn}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Three rows of 3 MB fit two per request
+	value := strings.Repeat("x", 3*1024*1024)
+	requests, err := encodeRequests(descriptor, [][]any{{value}, {value}, {value}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || len(requests[0].rows) != 2 || len(requests[1].rows) != 1 {
+		t.Fatalf("unexpected requests: %d", len(requests))
+	}
+	if requests[0].offset != 0 || requests[1].offset != 2 {
+		t.Fatalf("unexpected offsets %d and %d", requests[0].offset, requests[1].offset)
+	}
+
+	if _, err := encodeRequests(descriptor, [][]any{{int64(1)}, {value}}); err == nil {
+		t.Fatal("expected an error for a value which doesn't match the column")
+	}
+}
+
+func TestEncodeRepeatedRow(t *testing.T) {
+	descriptor, err := rowDescriptor(schema.Table{Columns: []schema.Column{{Name: "topics", Type: schema.StringColumn, Repeated: true}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := encodeRow(descriptor, []any{[]any{"0xa", "0xb"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := dynamicpb.NewMessage(descriptor)
+	if err := proto.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+	list := message.Get(descriptor.Fields().Get(0)).List()
+	if list.Len() != 2 || list.Get(0).String() != "0xa" || list.Get(1).String() != "0xb" {
+		t.Fatalf("unexpected topics: %v", list)
+	}
+
+	if _, err := encodeRow(descriptor, []any{[]any{int64(1)}}); err == nil {
+		t.Fatal("expected an error for an element which doesn't match the column")
+	}
+}
+
+func TestStorageErrorCode(t *testing.T) {
+	st, err := status.New(codes.AlreadyExists, "offset already exists").
+		WithDetails(&storagepb.StorageError{Code: storagepb.StorageError_OFFSET_ALREADY_EXISTS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiErr, ok := apierror.FromError(st.Err())
+	if !ok {
+		t.Fatal("expected an api error")
+	}
+
+	if code := storageErrorCode(apiErr); code != storagepb.StorageError_OFFSET_ALREADY_EXISTS {
+		t.Fatalf("got %s", code)
+	}
+	if code := storageErrorCode(status.Error(codes.Unavailable, "unavailable")); code != storagepb.StorageError_STORAGE_ERROR_CODE_UNSPECIFIED {
+		t.Fatalf("got %s", code)
+	}
+}
+
+func TestCommitQueueOrder(t *testing.T) {
+	queue := newCommitQueue()
+	queue.add(pendingCommit{sequence: 1})
+	queue.add(pendingCommit{sequence: 3})
+
+	taken := make(chan []pendingCommit)
+	go func() {
+		taken <- queue.take(0)
+	}()
+	select {
+	case commits := <-taken:
+		t.Fatalf("took %d commits before the first range arrived", len(commits))
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	queue.add(pendingCommit{sequence: 0})
+	if commits := <-taken; len(commits) != 2 || commits[0].sequence != 0 || commits[1].sequence != 1 {
+		t.Fatalf("expected the consecutive ranges 0 and 1, got %+v", commits)
+	}
+
+	// The range in front of 3 never arrives
+	queue.close()
+	if commits := queue.take(2); len(commits) != 0 {
+		t.Fatalf("expected no commits, got %+v", commits)
+	}
+	if remaining := queue.remaining(); len(remaining) != 1 || remaining[0].sequence != 3 {
+		t.Fatalf("expected the remaining range 3, got %+v", remaining)
+	}
+}
+
+func TestUncommittedStreams(t *testing.T) {
+	names := []string{"a", "b"}
+
+	remaining, err := uncommittedStreams(names, []*storagepb.StorageError{
+		{Code: storagepb.StorageError_STREAM_ALREADY_COMMITTED, Entity: "a"},
+	})
+	var permanentErr *utils.PermanentError
+	if err == nil || errors.As(err, &permanentErr) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+	if len(remaining) != 1 || remaining[0] != "b" {
+		t.Fatalf("expected stream b to remain, got %v", remaining)
+	}
+
+	// Committing a stream which isn't finalized fails again on every retry
+	_, err = uncommittedStreams(names, []*storagepb.StorageError{
+		{Code: storagepb.StorageError_STREAM_ALREADY_COMMITTED, Entity: "a"},
+		{Code: storagepb.StorageError_STREAM_NOT_FOUND, Entity: "b"},
+	})
+	if !errors.As(err, &permanentErr) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+
+	if remaining, err := uncommittedStreams(names, nil); err != nil || len(remaining) != 0 {
+		t.Fatalf("expected all streams to be committed, got %v, %v", remaining, err)
+	}
+}
@@ -2,12 +2,13 @@ package destinations
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/KYVENetwork/KYVE-DLT/schema"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
-	"strconv"
+	"math/big"
 	"strings"
 	"sync"
 )
@@ -17,7 +18,6 @@ type PostgresConfig struct {
 	TableName     string
 
 	PostgresWorkerCount int
-	RowInsertLimit      int
 }
 
 func NewPostgres(config PostgresConfig) Postgres {
@@ -36,6 +36,8 @@ type Postgres struct {
 	postgresWaitGroup sync.WaitGroup
 
 	schema schema.DataSource
+	// The progress is tracked in the first table
+	tables []schema.Table
 
 	logger zerolog.Logger
 }
@@ -49,7 +51,7 @@ func (p *Postgres) Close() {
 func (p *Postgres) GetLatestBundleId() *int64 {
 	stmt := fmt.Sprintf("SELECT MAX(%s) FROM %s",
 		"bundle_id",
-		p.config.TableName,
+		p.tables[0].FullName(p.config.TableName),
 	)
 
 	var latestBundleId *int64
@@ -63,6 +65,7 @@ func (p *Postgres) GetLatestBundleId() *int64 {
 
 func (p *Postgres) Initialize(schema schema.DataSource, destinationChannel chan DestinationBusItem) {
 	p.schema = schema
+	p.tables = schema.Tables()
 	p.dataRowChannel = destinationChannel
 
 	db, err := sql.Open("postgres", p.config.ConnectionUrl)
@@ -73,8 +76,10 @@ func (p *Postgres) Initialize(schema schema.DataSource, destinationChannel chan
 	p.db = db
 	p.logger.Info().Msg("postgres connection established")
 
-	if _, tableErr := p.db.Exec(p.schema.GetPostgresCreateTableCommand(p.config.TableName)); tableErr != nil {
-		panic(tableErr)
+	for _, table := range p.tables {
+		if _, tableErr := p.db.Exec(table.PostgresCreateTable(table.FullName(p.config.TableName))); tableErr != nil {
+			panic(tableErr)
+		}
 	}
 }
 
@@ -102,68 +107,102 @@ func (p *Postgres) postgresWorker(workerId string) {
 			return
 		}
 
-		utils.TryWithExponentialBackoff(func() error {
-			return p.bulkInsert(item.Data, p.config.RowInsertLimit)
-		}, func(err error) {
-			p.logger.Error().Str("worker-id", workerId).Str("err", err.Error()).Msg("PostgresWorker error, retry in 5 seconds")
-		})
+		count, err := p.insertRows(item.Rows)
+		item.Rows.done(err)
+		if item.Rows.aborted() != nil {
+			p.logger.Debug().Str("worker-id", workerId).Int64("fromBundleId", item.FromBundleId).Msg("discarded rows of aborted range")
+			continue
+		}
+		if err != nil {
+			p.logger.Error().
+				Str("worker-id", workerId).
+				Int64("fromBundleId", item.FromBundleId).
+				Int64("toBundleId", item.ToBundleId).
+				Str("err", err.Error()).
+				Msg("failed to insert rows, the range is converted again")
+			continue
+		}
 
 		p.logger.Info().
 			Str("worker-id", workerId).
 			Int64("fromBundleId", item.FromBundleId).
 			Int64("toBundleId", item.ToBundleId).
-			Int("rows", len(item.Data)).
+			Int("rows", count).
 			Msg("inserted")
 	}
 }
 
-func (p *Postgres) bulkInsert(items []schema.DataRow, rowLimit int) error {
-	columnNames := p.schema.GetCSVSchema()
-
-	argsCounter := 1
-	templateStrings := make([]string, 0, len(items))
-	valueArgs := make([]interface{}, 0, len(items))
-	selectedRows := 0
-	for _, row := range items {
-		selectedRows++
-		s := make([]string, len(columnNames))
-		for i := range s {
-			s[i] = "$" + strconv.FormatInt(int64(argsCounter), 10)
-			argsCounter += 1
-		}
-		templateString := fmt.Sprintf("(%s)", strings.Join(s, ", "))
-		templateStrings = append(templateStrings, templateString)
-		for _, field := range row.ConvertToCSVLine() {
-			valueArgs = append(valueArgs, field)
-		}
+// insertRows inserts the rows of all tables in one transaction, so that the progress never
+// includes partially inserted bundles. Every chunk is copied once it arrives.
+func (p *Postgres) insertRows(rows *RowStream) (int, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return 0, err
+	}
 
-		if selectedRows == rowLimit {
-			stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-				p.config.TableName,
-				"\""+strings.Join(columnNames, "\", \"")+"\"",
-				strings.Join(templateStrings, ", "),
-			)
-			_, err := p.db.Exec(stmt, valueArgs...)
-			if err != nil {
-				return err
+	var count int
+	for chunk := rows.next(); chunk != nil; chunk = rows.next() {
+		for _, table := range p.tables {
+			if len(chunk.rows[table.Name]) == 0 {
+				continue
+			}
+			if err := copyRows(tx, table.FullName(p.config.TableName), table.ColumnNames(), chunk.rows[table.Name]); err != nil {
+				_ = tx.Rollback()
+				return 0, err
 			}
-			argsCounter = 1
-			templateStrings = make([]string, 0, len(items))
-			valueArgs = make([]interface{}, 0, len(items))
-			selectedRows = 0
 		}
+		count += chunk.count
+	}
+	if err := rows.aborted(); err != nil {
+		_ = tx.Rollback()
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// copyRows streams the rows with COPY, the values are bound with their native types.
+func copyRows(tx *sql.Tx, tableName string, columnNames []string, rows [][]any) error {
+	stmt, err := tx.Prepare(fmt.Sprintf("COPY %s (%s) FROM STDIN",
+		tableName,
+		"\""+strings.Join(columnNames, "\", \"")+"\"",
+	))
+	if err != nil {
+		return err
 	}
 
-	if selectedRows >= 1 {
-		stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-			p.config.TableName,
-			"\""+strings.Join(columnNames, "\", \"")+"\"",
-			strings.Join(templateStrings, ", "),
-		)
-		_, err := p.db.Exec(stmt, valueArgs...)
-		if err != nil {
+	for _, values := range rows {
+		for i, value := range values {
+			values[i] = postgresValue(value)
+		}
+		if _, err := stmt.Exec(values...); err != nil {
+			_ = stmt.Close()
 			return err
 		}
 	}
-	return nil
+
+	// Flushes the remaining rows
+	if _, err := stmt.Exec(); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// postgresValue converts the values which can't be bound directly,
+// []byte is written as bytea.
+func postgresValue(value any) any {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case json.RawMessage:
+		return string(v)
+	case []any:
+		elements := make([]any, len(v))
+		for i, element := range v {
+			elements[i] = postgresValue(element)
+		}
+		return pq.Array(elements)
+	default:
+		return v
+	}
 }
@@ -0,0 +1,30 @@
+package destinations
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestPostgresValue(t *testing.T) {
+	if value := postgresValue(big.NewInt(12)); value != "12" {
+		t.Fatalf("got %#v", value)
+	}
+	if value := postgresValue(json.RawMessage(`{"a":1}`)); value != `{"a":1}` {
+		t.Fatalf("got %#v", value)
+	}
+
+	// Repeated columns are written as Postgres arrays
+	valuer, ok := postgresValue([]any{"0xa", "0xb"}).(driver.Valuer)
+	if !ok {
+		t.Fatal("expected an array")
+	}
+	value, err := valuer.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != `{"0xa","0xb"}` {
+		t.Fatalf("got %#v", value)
+	}
+}
@@ -0,0 +1,221 @@
+package destinations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// DefaultRowChunkSize is the size of the chunks if the memory budget allows it.
+const DefaultRowChunkSize = 16 * 1024 * 1024
+
+var errStoppedReading = errors.New("destination stopped reading rows")
+
+// RowChunkSize returns the chunk size for the given budget. Every worker holds at most
+// two chunks, the one it fills and the one the destination is writing, in half of its
+// share of the budget. The other half is left for the bundle it converts, so that the
+// workers can never wait for each other's memory.
+func RowChunkSize(memoryBudget int64, workers int) int64 {
+	if memoryBudget <= 0 {
+		return DefaultRowChunkSize
+	}
+	return max(min(DefaultRowChunkSize, memoryBudget/int64(4*max(workers, 1))), 1)
+}
+
+// RowStream passes the rows of a range from the worker which converts the bundles to
+// the destination in chunks, so that only a few chunks are in memory instead of all rows
+// of the range. The worker calls Write for every row and then Close or Abort.
+type RowStream struct {
+	ctx       context.Context
+	memory    *utils.MemoryGovernor
+	chunkSize int64
+
+	// Chunk filled by the worker
+	chunk  *rowChunk
+	chunks chan *rowChunk
+	// Set by Abort before chunks is closed
+	abortErr error
+
+	// Chunk held by the destination and whether all chunks were read
+	current *rowChunk
+	drained bool
+
+	doneOnce sync.Once
+	doneCh   chan struct{}
+	doneErr  error
+}
+
+// rowChunk holds the values of the rows grouped by table name.
+type rowChunk struct {
+	rows        map[string][][]any
+	count       int
+	size        int64
+	reservation *utils.MemoryReservation
+}
+
+// NewRowStream returns a stream whose chunks are reserved in the memory budget,
+// waiting for memory stops if ctx is canceled.
+func NewRowStream(ctx context.Context, memory *utils.MemoryGovernor, chunkSize int64) *RowStream {
+	return &RowStream{
+		ctx:       ctx,
+		memory:    memory,
+		chunkSize: max(chunkSize, 1),
+		chunks:    make(chan *rowChunk),
+		doneCh:    make(chan struct{}),
+	}
+}
+
+// Write adds the row to the current chunk and passes the chunk to the destination once it is full.
+// An error is returned if the destination failed, the stream has to be aborted then.
+func (s *RowStream) Write(row schema.DataRow) error {
+	if s.chunk == nil {
+		reservation, err := s.memory.Reserve(s.ctx, s.chunkSize)
+		if err != nil {
+			return err
+		}
+		s.chunk = &rowChunk{rows: make(map[string][][]any), reservation: reservation}
+	}
+
+	values := row.Values()
+	s.chunk.rows[row.TableName()] = append(s.chunk.rows[row.TableName()], values)
+	s.chunk.count++
+	s.chunk.size += rowSize(values)
+	// A single row can exceed the chunk size, the reservation has to cover it nevertheless
+	s.chunk.reservation.Grow(s.chunk.size)
+
+	if s.chunk.size >= s.chunkSize {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *RowStream) flush() error {
+	chunk := s.chunk
+	if chunk == nil {
+		return nil
+	}
+	s.chunk = nil
+	chunk.reservation.Shrink(chunk.size)
+
+	select {
+	case s.chunks <- chunk:
+		return nil
+	case <-s.doneCh:
+		chunk.reservation.Release()
+		if s.doneErr != nil {
+			return s.doneErr
+		}
+		return errStoppedReading
+	case <-s.ctx.Done():
+		chunk.reservation.Release()
+		return s.ctx.Err()
+	}
+}
+
+// Close passes the remaining rows to the destination and waits until it has written all rows
+// of the stream. If an error is returned, the rows were not written and the range has to be
+// converted again. Waiting stops if ctx is canceled.
+func (s *RowStream) Close() error {
+	if err := s.flush(); err != nil {
+		s.Abort(err)
+		return err
+	}
+	close(s.chunks)
+
+	select {
+	case <-s.doneCh:
+		return s.doneErr
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Abort discards the rows, the destination doesn't write any rows of the stream.
+func (s *RowStream) Abort(err error) {
+	if s.chunk != nil {
+		s.chunk.reservation.Release()
+		s.chunk = nil
+	}
+	s.abortErr = err
+	close(s.chunks)
+}
+
+// next returns the next chunk, or nil if all rows were read or the stream was aborted.
+// The previous chunk is released and must not be used anymore.
+func (s *RowStream) next() *rowChunk {
+	s.release()
+
+	chunk, ok := <-s.chunks
+	if !ok {
+		s.drained = true
+		return nil
+	}
+	s.current = chunk
+	return chunk
+}
+
+// aborted returns the error of Abort, it is nil until next returned nil.
+func (s *RowStream) aborted() error {
+	if !s.drained {
+		return nil
+	}
+	return s.abortErr
+}
+
+// done is called by the destination exactly once, with the error if the rows couldn't be
+// written. It can be called before all chunks were read, the worker then stops writing.
+func (s *RowStream) done(err error) {
+	s.doneOnce.Do(func() {
+		s.release()
+		s.doneErr = err
+		close(s.doneCh)
+	})
+}
+
+func (s *RowStream) release() {
+	if s.current != nil {
+		s.current.reservation.Release()
+		s.current = nil
+	}
+}
+
+// rowSize estimates the memory of the values of a row.
+func rowSize(values []any) int64 {
+	size := int64(24)
+	for _, value := range values {
+		size += valueSize(value)
+	}
+	return size
+}
+
+func valueSize(value any) int64 {
+	// Every value is held in an interface
+	const header = 16
+
+	switch v := value.(type) {
+	case string:
+		return header + 16 + int64(len(v))
+	case []byte:
+		return header + 24 + int64(len(v))
+	case json.RawMessage:
+		return header + 24 + int64(len(v))
+	case *big.Int:
+		return header + 32 + int64(len(v.Bits())*8)
+	case time.Time:
+		return header + 24
+	case []any:
+		size := int64(header + 24)
+		for _, element := range v {
+			size += valueSize(element)
+		}
+		return size
+	default:
+		return header + 8
+	}
+}
@@ -0,0 +1,175 @@
+package destinations
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+type testRow struct {
+	table string
+	value string
+}
+
+func (r testRow) TableName() string {
+	return r.table
+}
+
+func (r testRow) Values() []any {
+	return []any{r.value}
+}
+
+// readStream reads all chunks like a destination and returns the values per table.
+func readStream(rows *RowStream, result error) (map[string][]string, error) {
+	values := make(map[string][]string)
+	for chunk := rows.next(); chunk != nil; chunk = rows.next() {
+		for table, tableRows := range chunk.rows {
+			for _, row := range tableRows {
+				values[table] = append(values[table], row[0].(string))
+			}
+		}
+	}
+	err := rows.aborted()
+	if err == nil {
+		err = result
+	}
+	rows.done(err)
+	return values, err
+}
+
+func TestRowStreamChunks(t *testing.T) {
+	memory := utils.NewMemoryGovernor("test", 1024)
+	chunkSize := RowChunkSize(1024, 2)
+	rows := NewRowStream(context.Background(), memory, chunkSize)
+
+	type readResult struct {
+		values map[string][]string
+		err    error
+	}
+	read := make(chan readResult)
+	go func() {
+		values, err := readStream(rows, nil)
+		read <- readResult{values, err}
+	}()
+
+	// The rows of 100 values are larger than the budget, they only fit because chunks are released
+	for i := 0; i < 100; i++ {
+		table := "blocks"
+		if i%2 == 1 {
+			table = "transactions"
+		}
+		if err := rows.Write(testRow{table: table, value: string(rune('a' + i%26))}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := <-read
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if len(result.values["blocks"]) != 50 || len(result.values["transactions"]) != 50 {
+		t.Fatalf("unexpected rows: %d blocks, %d transactions", len(result.values["blocks"]), len(result.values["transactions"]))
+	}
+}
+
+func TestRowStreamAbort(t *testing.T) {
+	memory := utils.NewMemoryGovernor("test", 0)
+	rows := NewRowStream(context.Background(), memory, 1)
+
+	read := make(chan error)
+	go func() {
+		_, err := readStream(rows, nil)
+		read <- err
+	}()
+
+	if err := rows.Write(testRow{table: "blocks", value: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	abortErr := errors.New("checksum mismatch")
+	rows.Abort(abortErr)
+
+	if err := <-read; !errors.Is(err, abortErr) {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+}
+
+func TestRowStreamDestinationError(t *testing.T) {
+	memory := utils.NewMemoryGovernor("test", 0)
+	rows := NewRowStream(context.Background(), memory, 1)
+
+	// The destination fails after the first chunk and stops reading
+	writeErr := errors.New("connection reset")
+	go func() {
+		rows.next()
+		rows.done(writeErr)
+	}()
+
+	var err error
+	for i := 0; i < 10 && err == nil; i++ {
+		err = rows.Write(testRow{table: "blocks", value: "a"})
+	}
+	if !errors.Is(err, writeErr) {
+		t.Fatalf("expected destination error, got %v", err)
+	}
+	rows.Abort(err)
+}
+
+func TestRowStreamReleasesMemory(t *testing.T) {
+	memory := utils.NewMemoryGovernor("test", 100)
+	rows := NewRowStream(context.Background(), memory, 50)
+
+	go func() {
+		_, _ = readStream(rows, nil)
+	}()
+	for i := 0; i < 10; i++ {
+		if err := rows.Write(testRow{table: "blocks", value: "abcdefghijklmnopqrstuvwxyz"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The whole budget is available again
+	reservation, err := memory.Reserve(context.Background(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.Release()
+}
+
+func TestRowStreamOversizedRow(t *testing.T) {
+	memory := utils.NewMemoryGovernor("test", 1000)
+	rows := NewRowStream(context.Background(), memory, 10)
+
+	go func() {
+		if err := rows.Write(testRow{table: "blocks", value: string(make([]byte, 200))}); err != nil {
+			t.Error(err)
+		}
+		rows.Abort(errors.New("stop"))
+	}()
+
+	// The reservation covers the row although it is larger than the chunk size
+	chunk := rows.next()
+	if chunk == nil {
+		t.Fatal("expected a chunk")
+	}
+	if chunk.reservation.Size() < chunk.size {
+		t.Fatalf("reservation of %d bytes for a chunk of %d bytes", chunk.reservation.Size(), chunk.size)
+	}
+	for rows.next() != nil {
+	}
+	rows.done(rows.aborted())
+
+	// The whole budget is available again
+	reservation, err := memory.Reserve(context.Background(), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reservation.Release()
+}
@@ -13,7 +13,45 @@ type Destination interface {
 }
 
 type DestinationBusItem struct {
-	Data         []schema.DataRow
+	// Rows are passed while the bundles of the range are converted
+	Rows         *RowStream
 	FromBundleId int64
 	ToBundleId   int64
+	// Sequence is the position of the range in the order the bundles were fetched,
+	// starting at 0. Items can arrive out of order when several workers convert bundles.
+	Sequence int64
+}
+
+// commitOrder lets workers prepare items concurrently, but commits them in the order of
+// their sequence. The progress is the highest bundle_id of the first table, so it is only
+// correct if a range is never committed before all ranges in front of it.
+type commitOrder struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	next int64
+}
+
+func newCommitOrder() *commitOrder {
+	o := &commitOrder{}
+	o.cond = sync.NewCond(&o.mu)
+	return o
+}
+
+// wait blocks until all items before sequence were committed.
+func (o *commitOrder) wait(sequence int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for o.next != sequence {
+		o.cond.Wait()
+	}
+}
+
+// done marks the item as committed and releases the next one.
+func (o *commitOrder) done(sequence int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.next = sequence + 1
+	o.cond.Broadcast()
 }
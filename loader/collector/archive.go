@@ -0,0 +1,330 @@
+package collector
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// An archive is a local copy of a pool which can be used instead of the KYVE API:
+//
+//	<root>/bundles/<first bundle id>.json  pages in the format of /kyve/v1/bundles/{pool_id}
+//	<root>/storage/<storage_id>            raw bundle data as stored by the storage provider
+//
+// The root can either be a directory or an uncompressed tarball (.tar) of that directory. Tarballs
+// are read in place, the bundle data is compressed already, so compressed tarballs are not supported.
+const (
+	ArchiveBundlesDir = "bundles"
+	ArchiveStorageDir = "storage"
+)
+
+type Archive struct {
+	dir string
+	// Tarball the archive is read from and the position of the blobs in it
+	tarball *os.File
+	blobs   map[string]tarEntry
+
+	poolId string
+	// sorted by bundle id
+	bundles []Bundle
+	ids     []int64
+}
+
+type tarEntry struct {
+	offset int64
+	size   int64
+}
+
+// OpenArchive reads the bundle metadata of an archive directory or tarball.
+func OpenArchive(path string) (*Archive, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+
+	archive := &Archive{dir: path}
+	var pages map[string][]byte
+	if info.IsDir() {
+		pages, err = readPages(path)
+	} else {
+		pages, err = archive.openTarball(path)
+	}
+	if err == nil {
+		err = archive.readBundles(pages)
+	}
+	if err != nil {
+		archive.Close()
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Close closes the tarball of the archive.
+func (a *Archive) Close() {
+	if a.tarball != nil {
+		_ = a.tarball.Close()
+	}
+}
+
+// PoolId returns the pool the bundles of the archive belong to.
+func (a *Archive) PoolId() string {
+	return a.poolId
+}
+
+func readPages(dir string) (map[string][]byte, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, ArchiveBundlesDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		pages[path] = data
+	}
+	return pages, nil
+}
+
+func (a *Archive) readBundles(pages map[string][]byte) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("archive %s does not contain any bundles", a.dir)
+	}
+
+	bundles := make(map[int64]Bundle)
+	for page, data := range pages {
+		var response Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", page, err)
+		}
+
+		for _, bundle := range response.FinalizedBundles {
+			id, err := strconv.ParseInt(bundle.Id, 10, 64)
+			if err != nil {
+				return fmt.Errorf("malformed bundle in %s, invalid bundle-id: %s", page, err.Error())
+			}
+			if a.poolId == "" {
+				a.poolId = bundle.PoolId
+			} else if bundle.PoolId != a.poolId {
+				return fmt.Errorf("archive %s contains bundles of pool %s and %s", a.dir, a.poolId, bundle.PoolId)
+			}
+			bundles[id] = bundle
+		}
+	}
+
+	for id := range bundles {
+		a.ids = append(a.ids, id)
+	}
+	sort.Slice(a.ids, func(i, j int) bool { return a.ids[i] < a.ids[j] })
+	for _, id := range a.ids {
+		a.bundles = append(a.bundles, bundles[id])
+	}
+	return nil
+}
+
+// page returns bundles in the same way the KYVE API does. The pagination key is the
+// index of the next bundle, the initial page starts at the first bundle id >= offset.
+func (a *Archive) page(offset int64, paginationKey string, limit int64) (*Response, error) {
+	start := sort.Search(len(a.ids), func(i int) bool { return a.ids[i] >= offset })
+	if paginationKey != "" {
+		index, err := strconv.Atoi(paginationKey)
+		if err != nil || index < 0 || index > len(a.bundles) {
+			return nil, fatal("invalid pagination key %q", paginationKey)
+		}
+		start = index
+	}
+
+	if limit <= 0 {
+		limit = int64(len(a.bundles))
+	}
+	end := min(start+int(limit), len(a.bundles))
+
+	response := &Response{FinalizedBundles: a.bundles[start:end]}
+	if end < len(a.bundles) {
+		response.Pagination.NextKey = strconv.Itoa(end)
+	}
+	response.Pagination.Total = strconv.Itoa(len(a.bundles))
+	return response, nil
+}
+
+// single returns the first bundle with an id >= offset or the latest bundle if reverse is set.
+func (a *Archive) single(offset int64, reverse bool) *Response {
+	response := &Response{}
+	if reverse {
+		if len(a.bundles) > 0 {
+			response.FinalizedBundles = a.bundles[len(a.bundles)-1:]
+		}
+		return response
+	}
+
+	start := sort.Search(len(a.ids), func(i int) bool { return a.ids[i] >= offset })
+	if start < len(a.bundles) {
+		response.FinalizedBundles = a.bundles[start : start+1]
+	}
+	return response
+}
+
+// ReadBlob returns the raw data of a bundle as stored by the storage provider.
+func (a *Archive) ReadBlob(storageId string) ([]byte, error) {
+	blob, err := a.OpenBlob(storageId)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+	return io.ReadAll(blob)
+}
+
+// OpenBlob returns a reader of the raw data of a bundle as stored by the storage provider.
+func (a *Archive) OpenBlob(storageId string) (io.ReadCloser, error) {
+	path, err := blobPath(a.dir, storageId)
+	if err != nil {
+		return nil, err
+	}
+	if a.tarball == nil {
+		return os.Open(path)
+	}
+
+	entry, ok := a.blobs[storageId]
+	if !ok {
+		return nil, fmt.Errorf("open %s: %w", path, os.ErrNotExist)
+	}
+	return blobReader{io.NewSectionReader(a.tarball, entry.offset, entry.size)}, nil
+}
+
+// blobReader reads a blob of a tarball, whose file is closed with the archive.
+type blobReader struct {
+	*io.SectionReader
+}
+
+func (blobReader) Close() error {
+	return nil
+}
+
+func blobPath(dir, storageId string) (string, error) {
+	if storageId == "" || strings.ContainsAny(storageId, `/\`) || storageId == "." || storageId == ".." {
+		return "", fmt.Errorf("invalid storage id %q", storageId)
+	}
+	return filepath.Join(dir, ArchiveStorageDir, storageId), nil
+}
+
+// openTarball indexes the blobs of the tarball and returns the bundle pages, which are small
+// enough to be read into memory.
+func (a *Archive) openTarball(path string) (map[string][]byte, error) {
+	if strings.HasSuffix(path, ".gz") || strings.HasSuffix(path, ".tgz") {
+		return nil, fmt.Errorf("compressed archive %s is not supported, use an uncompressed tarball or directory", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	a.tarball = f
+	a.blobs = make(map[string]tarEntry)
+
+	// The tar reader doesn't read ahead, so the position of the file is the start of the entry data
+	pages := make(map[string][]byte)
+	tarReader := tar.NewReader(f)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return pages, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Only the archive layout is read, the tarball may contain the root directory
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		parts := strings.Split(name, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		dir, file := parts[len(parts)-2], parts[len(parts)-1]
+
+		switch {
+		case dir == ArchiveBundlesDir && filepath.Ext(file) == ".json":
+			var page bytes.Buffer
+			if _, err := io.Copy(&page, tarReader); err != nil {
+				return nil, fmt.Errorf("failed to read archive: %w", err)
+			}
+			pages[name] = page.Bytes()
+		case dir == ArchiveStorageDir:
+			offset, err := f.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			a.blobs[file] = tarEntry{offset: offset, size: header.Size}
+		}
+	}
+}
+
+// ArchiveWriter stores bundles in the archive layout, so that they can be loaded with an offline source.
+type ArchiveWriter struct {
+	dir string
+}
+
+func NewArchiveWriter(dir string) (*ArchiveWriter, error) {
+	for _, subDir := range []string{ArchiveBundlesDir, ArchiveStorageDir} {
+		if err := os.MkdirAll(filepath.Join(dir, subDir), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &ArchiveWriter{dir: dir}, nil
+}
+
+// ReadBlob returns the stored data of a bundle, it is not verified.
+func (w *ArchiveWriter) ReadBlob(storageId string) ([]byte, error) {
+	path, err := blobPath(w.dir, storageId)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// WriteBlob stores the raw data of a bundle.
+func (w *ArchiveWriter) WriteBlob(storageId string, data []byte) error {
+	path, err := blobPath(w.dir, storageId)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// WritePage stores the metadata of the bundles. It should be called after all
+// blobs of the page were written, so that the archive is always complete.
+func (w *ArchiveWriter) WritePage(bundles []Bundle) error {
+	if len(bundles) == 0 {
+		return nil
+	}
+
+	id, err := strconv.ParseInt(bundles[0].Id, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid bundle-id: %s", err.Error())
+	}
+
+	data, err := json.MarshalIndent(Response{FinalizedBundles: bundles}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(w.dir, ArchiveBundlesDir, fmt.Sprintf("%020d.json", id)), data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testArchiveDir contains three bundles of pool 1 with three height items each.
+const testArchiveDir = "testdata/archive"
+
+// writeTestTarball packs the test archive into a tarball with a root directory.
+func writeTestTarball(t *testing.T) string {
+	t.Helper()
+
+	var data bytes.Buffer
+	writer := tar.NewWriter(&data)
+	err := filepath.WalkDir(testArchiveDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(filepath.Join("archive", strings.TrimPrefix(path, testArchiveDir)))
+		if err := writer.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		_, err = writer.Write(content)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "archive.tar")
+	if err := os.WriteFile(path, data.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		path func(t *testing.T) string
+	}{
+		{name: "directory", path: func(t *testing.T) string { return testArchiveDir }},
+		{name: "tarball", path: writeTestTarball},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive, err := OpenArchive(tt.path(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer archive.Close()
+
+			source, err := NewSource(SourceConfig{PoolId: 1, ToBundleId: 100, BatchSize: 2, Archive: archive})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids, err := collectAll(t, source.Bundles(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertIds(t, ids, []int64{0, 1, 2})
+
+			for _, bundle := range archive.bundles {
+				want, err := os.ReadFile(filepath.Join(testArchiveDir, ArchiveStorageDir, bundle.StorageId))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := archive.ReadBlob(bundle.StorageId)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Fatalf("blob %s differs", bundle.StorageId)
+				}
+			}
+
+			if _, err := archive.OpenBlob("missing"); err == nil {
+				t.Fatal("expected error for missing blob")
+			}
+		})
+	}
+}
+
+func TestOpenArchiveCompressedTarball(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.tar.gz")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenArchive(path); err == nil {
+		t.Fatal("expected compressed tarball to be rejected")
+	}
+}
+
+func TestArchivePoolMismatch(t *testing.T) {
+	archive, err := OpenArchive(testArchiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	if _, err := NewSource(SourceConfig{PoolId: 2, ToBundleId: 100, Archive: archive}); err == nil {
+		t.Fatal("expected archive of another pool to be rejected")
+	}
+}
+
+func TestBundleIteratorArchiveToBundleId(t *testing.T) {
+	archive, err := OpenArchive(writeTestTarball(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	source, err := NewSource(SourceConfig{PoolId: 1, ToBundleId: 1, BatchSize: 10, Archive: archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bundles, err := source.Bundles(0).Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 2 {
+		t.Fatalf("expected the bundles up to to_bundle_id 1, got %d", len(bundles))
+	}
+}
@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
-	"io/ioutil"
+	"io"
 	"net/http"
+	"net/url"
 	"strconv"
-	"strings"
+	"time"
 )
 
 var (
@@ -30,8 +31,28 @@ func NewSource(config SourceConfig) (Source, error) {
 		return Source{}, errors.New("invalid to-bundle-id")
 	}
 
-	if strings.HasSuffix(config.Endpoint, "/") {
-		config.Endpoint = config.Endpoint[:len(config.Endpoint)-1]
+	if config.Follow != nil && config.Follow.PollInterval <= 0 {
+		return Source{}, errors.New("invalid poll-interval, it has to be positive")
+	}
+
+	if config.Archive != nil {
+		if poolId := config.Archive.PoolId(); poolId != strconv.FormatInt(config.PoolId, 10) {
+			return Source{}, fmt.Errorf("archive contains bundles of pool %s instead of pool %d", poolId, config.PoolId)
+		}
+		return Source{
+			poolId:       config.PoolId,
+			fromBundleId: config.FromBundleId,
+			toBundleId:   config.ToBundleId,
+			batchSize:    config.BatchSize,
+			follow:       config.Follow,
+			trustPolicy:  config.TrustPolicy,
+			archive:      config.Archive,
+		}, nil
+	}
+
+	c, err := newClient(config.Endpoints, config.Client)
+	if err != nil {
+		return Source{}, err
 	}
 
 	return Source{
@@ -39,139 +60,236 @@ func NewSource(config SourceConfig) (Source, error) {
 		fromBundleId: config.FromBundleId,
 		toBundleId:   config.ToBundleId,
 		batchSize:    config.BatchSize,
-		endpoint:     config.Endpoint,
+		follow:       config.Follow,
+		trustPolicy:  config.TrustPolicy,
+		client:       c,
 	}, nil
 }
 
+// FetchBundles passes all bundles starting from offset page by page to the handler.
+// Retryable errors are passed to the handler before the page is requested again,
+// a fatal error stops the process.
 func (s Source) FetchBundles(ctx context.Context, offset int64, connectionName string, handler func(bundles []Bundle, err error)) {
-	response, responseError := http.Get(
-		fmt.Sprintf(
-			"%s/kyve/v1/bundles/%d?pagination.limit=%d&pagination.offset=%d",
-			s.endpoint,
-			s.poolId,
-			s.batchSize,
-			offset,
-		))
-	if responseError != nil {
-		handler(nil, fmt.Errorf("initial bundle request failed: %s", responseError.Error()))
+	if s.client != nil && len(s.client.endpoints) > 1 {
+		healthCheckCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		go s.client.runHealthChecks(healthCheckCtx)
 	}
-	defer response.Body.Close()
 
-	// Handle Initial Bundle request
-	initialBundles, paginationKey, bodyError := handleBody(response)
-	if bodyError != nil {
-		handler(nil, fmt.Errorf("initial bundle request failed: %s", responseError.Error()))
+	it := s.Bundles(offset)
+	for {
+		bundles, err := it.Next(ctx)
+		if err != nil {
+			// Graceful shutdown
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, ErrDone) {
+				logger.Info().Str("connection", connectionName).Msg("reached last bundle")
+				return
+			}
+
+			handler(nil, err)
+			if !IsRetryable(err) {
+				return
+			}
+			continue
+		}
+
+		if len(bundles) > 0 {
+			handler(bundles, nil)
+		}
 	}
+}
 
-	if len(initialBundles) == 0 {
-		handler(nil, fmt.Errorf("could not find any bundles yet; from-bundle-id too high or cron interval too short"))
-		return
+// BundleIterator pages through the finalized bundles of a pool until the last
+// bundle or the to-bundle-id is reached. In follow mode, it keeps polling for new
+// bundles after the last bundle was reached.
+type BundleIterator struct {
+	source Source
+
+	offset        int64
+	paginationKey string
+
+	started bool
+	done    bool
+
+	// Follow mode: the latest bundle was reached and the next poll is delayed
+	caughtUp bool
+	delay    time.Duration
+
+	// Trust policy: the next bundle is held back until holdUntil or violated the policy
+	holdUntil time.Time
+	err       error
+}
+
+// Bundles returns an iterator over all bundles starting at offset.
+func (s Source) Bundles(offset int64) *BundleIterator {
+	return &BundleIterator{
+		source: s,
+		offset: offset,
 	}
+}
 
-	highestBundleId, err := strconv.ParseInt(initialBundles[len(initialBundles)-1].Id, 10, 64)
-	if err != nil {
-		handler(nil, fmt.Errorf("malformed bundle response, invalid bundle-id: %s", err.Error()))
-		return
+// Next returns the bundles of the next page, which can be empty. After the last
+// bundle ErrDone is returned. If a RetryableError is returned, the iterator
+// remains unchanged and Next can be called again.
+func (it *BundleIterator) Next(ctx context.Context) ([]Bundle, error) {
+	if it.done {
+		return nil, ErrDone
 	}
 
-	if highestBundleId > s.toBundleId || paginationKey == "" {
-		logger.Info().Str("connection", connectionName).Msg("reached last bundle")
+	if it.err != nil {
+		return nil, it.err
+	}
 
-		var bundles []Bundle
-		for _, b := range initialBundles {
-			select {
-			// Graceful shutdown
-			case <-ctx.Done():
-				return
-			default:
-				bundleId, err := strconv.ParseInt(b.Id, 10, 64)
-				if err != nil {
-					handler(nil, fmt.Errorf("malformed bundle response, invalid bundle-id: %s", err.Error()))
-					return
-				}
-				if bundleId <= s.toBundleId {
-					bundles = append(bundles, b)
-				} else {
-					break
-				}
-			}
-		}
-		handler(bundles, nil)
-		return
-	} else {
-		handler(initialBundles, nil)
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Iterate remaining bundles
-	currentBundleId := offset
-	for currentBundleId < s.toBundleId {
+	if !it.holdUntil.IsZero() {
 		select {
-		// Graceful shutdown
 		case <-ctx.Done():
-			return
-		default:
-			newBundles, nextKey, err := s.fetch(paginationKey)
-			if err != nil {
-				handler(nil, err)
-				continue
-			}
+			return nil, ctx.Err()
+		case <-time.After(time.Until(it.holdUntil)):
+		}
+		it.holdUntil = time.Time{}
+	} else if it.caughtUp {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(it.delay):
+		}
+	}
 
-			bundles := make([]Bundle, 0)
-			for _, bundle := range newBundles {
-				id, idErr := strconv.ParseUint(bundle.Id, 10, 64)
-				if idErr != nil {
-					handler(nil, fmt.Errorf("malformed bundle response, invalid bundle-id: %s", idErr.Error()))
-					return
-				}
-				if int64(id) <= s.toBundleId {
-					currentBundleId = int64(id)
-					bundles = append(bundles, bundle)
-				} else {
-					break
-				}
-			}
-			handler(bundles, nil)
+	page, err := it.source.fetchPage(ctx, it.offset, it.paginationKey)
+	if err != nil {
+		return nil, err
+	}
 
-			if nextKey == "" {
-				logger.Info().Msg("reached last bundle")
-				return
+	if !it.started && len(page.FinalizedBundles) == 0 && it.source.follow == nil {
+		return nil, &FatalError{Err: ErrNoBundles}
+	}
+
+	bundles := make([]Bundle, 0, len(page.FinalizedBundles))
+	for _, b := range page.FinalizedBundles {
+		bundleId, err := strconv.ParseInt(b.Id, 10, 64)
+		if err != nil {
+			return nil, fatal("malformed bundle response, invalid bundle-id: %s", err.Error())
+		}
+		if bundleId > it.source.toBundleId {
+			it.done = true
+			break
+		}
+		bundles = append(bundles, b)
+		it.offset = bundleId + 1
+	}
+
+	it.started = true
+
+	if it.source.trustPolicy != nil && len(bundles) > 0 {
+		trusted, index, violation, err := it.source.trustPolicy.apply(bundles, time.Now())
+		if err != nil {
+			return nil, &FatalError{Err: err}
+		}
+		if violation != nil {
+			// Continue at the violating bundle, either after the hold or not at all
+			it.offset, _ = strconv.ParseInt(bundles[index].Id, 10, 64)
+			it.paginationKey = ""
+			it.caughtUp = false
+			it.done = false
+
+			if !violation.HoldUntil.IsZero() {
+				logger.Info().Str("bundle_id", violation.BundleId).Time("hold_until", violation.HoldUntil).Msg("holding back bundle until it is finalized long enough")
+				it.holdUntil = violation.HoldUntil
+			} else {
+				it.err = &FatalError{Err: violation}
 			}
-			paginationKey = nextKey
+			return trusted, nil
+		}
+		bundles = trusted
+	}
+
+	if it.done {
+		return bundles, nil
+	}
+
+	if page.Pagination.NextKey != "" {
+		it.paginationKey = page.Pagination.NextKey
+		it.caughtUp = false
+		return bundles, nil
+	}
+
+	// Follow mode ends once the to-bundle-id was reached
+	if it.source.follow == nil || it.offset > it.source.toBundleId {
+		it.done = true
+		return bundles, nil
+	}
+
+	// Poll for new bundles starting after the latest bundle
+	it.paginationKey = ""
+	if !it.caughtUp || len(bundles) > 0 {
+		if !it.caughtUp {
+			logger.Info().Int64("bundle_id", it.offset-1).Msg("reached latest bundle, waiting for new bundles")
 		}
+		it.delay = it.source.follow.PollInterval
+	} else {
+		it.delay = min(2*it.delay, max(it.source.follow.MaxPollInterval, it.source.follow.PollInterval))
 	}
+	it.caughtUp = true
+
+	return bundles, nil
 }
 
-func (s Source) fetch(paginationKey string) ([]Bundle, string, error) {
-	response, responseError := http.Get(
-		fmt.Sprintf(
-			"%s/kyve/v1/bundles/%d?pagination.limit=%d&pagination.key=%s",
-			s.endpoint,
-			s.poolId,
-			s.batchSize,
-			strings.ReplaceAll(paginationKey, "+", "%2b"),
-		))
-	if responseError != nil {
-		return nil, "", fmt.Errorf("bundle request failed: %s", responseError.Error())
-	}
-	if response.StatusCode != 200 {
-		return nil, "", fmt.Errorf("invalid status code: %d", response.StatusCode)
+// fetchPage requests a single page, the initial page is requested by offset
+// and all further pages by the pagination key of the previous page.
+func (s Source) fetchPage(ctx context.Context, offset int64, paginationKey string) (*Response, error) {
+	if s.archive != nil {
+		return s.archive.page(offset, paginationKey, s.batchSize)
+	}
+
+	query := url.Values{}
+	query.Set("pagination.limit", strconv.FormatInt(s.batchSize, 10))
+	if paginationKey == "" {
+		query.Set("pagination.offset", strconv.FormatInt(offset, 10))
+	} else {
+		query.Set("pagination.key", paginationKey)
+	}
+
+	return s.requestBundles(ctx, query)
+}
+
+func (s Source) requestBundles(ctx context.Context, query url.Values) (*Response, error) {
+	response, err := s.client.get(ctx, fmt.Sprintf("/kyve/v1/bundles/%d?%s", s.poolId, query.Encode()))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, retryable("bundle request failed: %s", err.Error())
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+			return nil, retryable("invalid status code: %d", response.StatusCode)
+		}
+		return nil, fatal("invalid status code: %d", response.StatusCode)
+	}
+
 	return handleBody(response)
 }
 
-func handleBody(resp *http.Response) (bundles []Bundle, nextKey string, err error) {
-	body, err := ioutil.ReadAll(resp.Body)
+func handleBody(resp *http.Response) (*Response, error) {
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, "", fmt.Errorf("reading response body failed: %s", err.Error())
+		return nil, retryable("reading response body failed: %s", err.Error())
 	}
 
 	var data Response
 	err = json.Unmarshal(body, &data)
 	if err != nil {
-		return nil, "", fmt.Errorf("parsing JSON failed: %s", err.Error())
+		return nil, fatal("parsing JSON failed: %s", err.Error())
 	}
 
-	return data.FinalizedBundles, data.Pagination.NextKey, nil
+	return &data, nil
 }
@@ -0,0 +1,375 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// page is a single response of the mocked bundles endpoint.
+type page struct {
+	bundleIds []int64
+	nextKey   string
+}
+
+// mockBundlesServer serves the initial page for the offset request and all
+// further pages by their pagination key.
+type mockBundlesServer struct {
+	t *testing.T
+
+	poolId  int64
+	initial page
+	pages   map[string]page
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func (m *mockBundlesServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.requests = append(m.requests, r.URL.RawQuery)
+	m.mu.Unlock()
+
+	if r.URL.Path != fmt.Sprintf("/kyve/v1/bundles/%d", m.poolId) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	p := m.initial
+	if key := r.URL.Query().Get("pagination.key"); key != "" {
+		var ok bool
+		p, ok = m.pages[key]
+		if !ok {
+			m.t.Errorf("unexpected pagination key %q", key)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	} else if r.URL.Query().Get("pagination.offset") == "" {
+		m.t.Errorf("initial request without offset: %s", r.URL.RawQuery)
+	}
+
+	var response Response
+	response.FinalizedBundles = make([]Bundle, 0, len(p.bundleIds))
+	for _, id := range p.bundleIds {
+		response.FinalizedBundles = append(response.FinalizedBundles, Bundle{
+			PoolId: strconv.FormatInt(m.poolId, 10),
+			Id:     strconv.FormatInt(id, 10),
+		})
+	}
+	response.Pagination.NextKey = p.nextKey
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.t.Fatal(err)
+	}
+}
+
+func newTestSource(t *testing.T, handler http.Handler, toBundleId int64) Source {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	source, err := NewSource(SourceConfig{
+		PoolId:       1,
+		FromBundleId: 0,
+		ToBundleId:   toBundleId,
+		BatchSize:    3,
+		Endpoints:    []string{server.URL},
+		Client: ClientConfig{
+			Timeout:    time.Second,
+			MaxRetries: 1,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func collectAll(t *testing.T, it *BundleIterator) ([]int64, error) {
+	t.Helper()
+
+	var ids []int64
+	for {
+		bundles, err := it.Next(context.Background())
+		if errors.Is(err, ErrDone) {
+			return ids, nil
+		}
+		if err != nil {
+			return ids, err
+		}
+		for _, b := range bundles {
+			id, _ := strconv.ParseInt(b.Id, 10, 64)
+			ids = append(ids, id)
+		}
+	}
+}
+
+func assertIds(t *testing.T, got []int64, want []int64) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got bundle ids %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got bundle ids %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBundleIteratorPagination(t *testing.T) {
+	server := &mockBundlesServer{
+		t:       t,
+		poolId:  1,
+		initial: page{bundleIds: []int64{0, 1, 2}, nextKey: "a+b/c=="},
+		pages: map[string]page{
+			"a+b/c==": {bundleIds: []int64{3, 4, 5}, nextKey: "next"},
+			"next":    {bundleIds: []int64{6}},
+		},
+	}
+
+	source := newTestSource(t, server, 100)
+	ids, err := collectAll(t, source.Bundles(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertIds(t, ids, []int64{0, 1, 2, 3, 4, 5, 6})
+	if len(server.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(server.requests))
+	}
+}
+
+func TestBundleIteratorEmptyPages(t *testing.T) {
+	server := &mockBundlesServer{
+		t:       t,
+		poolId:  1,
+		initial: page{bundleIds: []int64{0, 1, 2}, nextKey: "empty"},
+		pages: map[string]page{
+			"empty": {bundleIds: nil, nextKey: "last"},
+			"last":  {bundleIds: []int64{3}},
+		},
+	}
+
+	source := newTestSource(t, server, 100)
+	ids, err := collectAll(t, source.Bundles(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertIds(t, ids, []int64{0, 1, 2, 3})
+}
+
+func TestBundleIteratorNoBundles(t *testing.T) {
+	server := &mockBundlesServer{
+		t:      t,
+		poolId: 1,
+	}
+
+	source := newTestSource(t, server, 100)
+	_, err := source.Bundles(50).Next(context.Background())
+	if !errors.Is(err, ErrNoBundles) {
+		t.Fatalf("expected ErrNoBundles, got %v", err)
+	}
+	if IsRetryable(err) {
+		t.Fatal("ErrNoBundles should not be retryable")
+	}
+}
+
+func TestBundleIteratorToBundleIdCutOff(t *testing.T) {
+	tests := []struct {
+		name       string
+		toBundleId int64
+		want       []int64
+		requests   int
+	}{
+		{name: "within initial page", toBundleId: 1, want: []int64{0, 1}, requests: 1},
+		{name: "last of initial page", toBundleId: 2, want: []int64{0, 1, 2}, requests: 2},
+		{name: "within second page", toBundleId: 4, want: []int64{0, 1, 2, 3, 4}, requests: 2},
+		{name: "after last bundle", toBundleId: 100, want: []int64{0, 1, 2, 3, 4, 5}, requests: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &mockBundlesServer{
+				t:       t,
+				poolId:  1,
+				initial: page{bundleIds: []int64{0, 1, 2}, nextKey: "second"},
+				pages: map[string]page{
+					"second": {bundleIds: []int64{3, 4, 5}},
+				},
+			}
+
+			source := newTestSource(t, server, tt.toBundleId)
+			ids, err := collectAll(t, source.Bundles(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertIds(t, ids, tt.want)
+			if len(server.requests) != tt.requests {
+				t.Fatalf("expected %d requests, got %d", tt.requests, len(server.requests))
+			}
+		})
+	}
+}
+
+func TestBundleIteratorStatusCodes(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		retryable bool
+	}{
+		{name: "not found", status: http.StatusNotFound, retryable: false},
+		{name: "bad request", status: http.StatusBadRequest, retryable: false},
+		{name: "too many requests", status: http.StatusTooManyRequests, retryable: true},
+		{name: "internal server error", status: http.StatusInternalServerError, retryable: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}), 100)
+
+			_, err := source.Bundles(0).Next(context.Background())
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if IsRetryable(err) != tt.retryable {
+				t.Fatalf("expected retryable=%v, got %v", tt.retryable, err)
+			}
+		})
+	}
+}
+
+func TestBundleIteratorMalformedResponse(t *testing.T) {
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"finalized_bundles": [{"id": "abc"}]}`))
+	}), 100)
+
+	_, err := source.Bundles(0).Next(context.Background())
+	var fatalError *FatalError
+	if !errors.As(err, &fatalError) {
+		t.Fatalf("expected fatal error, got %v", err)
+	}
+}
+
+func TestBundleIteratorRetryKeepsPosition(t *testing.T) {
+	var failed bool
+	server := &mockBundlesServer{
+		t:       t,
+		poolId:  1,
+		initial: page{bundleIds: []int64{0, 1, 2}, nextKey: "second"},
+		pages: map[string]page{
+			"second": {bundleIds: []int64{3}},
+		},
+	}
+
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pagination.key") == "second" && !failed {
+			failed = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		server.ServeHTTP(w, r)
+	}), 100)
+
+	it := source.Bundles(0)
+	if _, err := it.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := it.Next(context.Background()); !IsRetryable(err) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+
+	bundles, err := it.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 1 || bundles[0].Id != "3" {
+		t.Fatalf("unexpected bundles after retry: %v", bundles)
+	}
+}
+
+func TestBundleIteratorContextCanceled(t *testing.T) {
+	source := newTestSource(t, &mockBundlesServer{t: t, poolId: 1}, 100)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := source.Bundles(0).Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBundleIteratorFollow(t *testing.T) {
+	// The pool grows by one bundle every second poll
+	var mu sync.Mutex
+	var polls int
+	var offsets []string
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		polls++
+		latest := int64(2 + polls/2)
+		offset, _ := strconv.ParseInt(r.URL.Query().Get("pagination.offset"), 10, 64)
+		offsets = append(offsets, r.URL.Query().Get("pagination.offset"))
+
+		var response Response
+		for id := offset; id <= latest && id < offset+3; id++ {
+			response.FinalizedBundles = append(response.FinalizedBundles, Bundle{Id: strconv.FormatInt(id, 10)})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}), 5)
+	source.follow = &FollowConfig{PollInterval: time.Millisecond, MaxPollInterval: 4 * time.Millisecond}
+
+	ids, err := collectAll(t, source.Bundles(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertIds(t, ids, []int64{0, 1, 2, 3, 4, 5})
+	if offsets[0] != "0" || offsets[1] != "3" {
+		t.Fatalf("expected polling to continue after the latest bundle, got offsets %v", offsets)
+	}
+}
+
+func TestBundleIteratorFollowWaitsForFirstBundle(t *testing.T) {
+	var polls int
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+
+		var response Response
+		if polls > 2 && r.URL.Query().Get("pagination.offset") == "0" {
+			response.FinalizedBundles = []Bundle{{Id: "0"}}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}), 0)
+	source.follow = &FollowConfig{PollInterval: time.Millisecond, MaxPollInterval: time.Millisecond}
+
+	ids, err := collectAll(t, source.Bundles(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIds(t, ids, []int64{0})
+}
+
+func TestNewSourceRejectsInvalidPollInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		_, err := NewSource(SourceConfig{PoolId: 1, ToBundleId: 10, Endpoints: []string{"http://localhost"}, Follow: &FollowConfig{PollInterval: interval}})
+		if err == nil {
+			t.Fatalf("expected poll interval %s to be rejected", interval)
+		}
+	}
+}
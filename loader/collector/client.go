@@ -0,0 +1,271 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const healthCheckPath = "/cosmos/base/tendermint/v1beta1/syncing"
+
+type ClientConfig struct {
+	// Timeout of a single request
+	Timeout time.Duration
+	// Number of attempts per request, spread across all endpoints
+	MaxRetries int
+	// Consecutive failures after which an endpoint is skipped
+	FailureThreshold int
+	// Time an endpoint is skipped before it is tried again
+	Cooldown time.Duration
+	// Interval of the background health checks
+	HealthCheckInterval time.Duration
+	// Delay before the first retry, it doubles after every failed attempt up to MaxRetryDelay
+	RetryDelay    time.Duration
+	MaxRetryDelay time.Duration
+}
+
+// endpoint keeps the circuit breaker state of a single REST endpoint.
+type endpoint struct {
+	url       string
+	failures  int
+	openUntil time.Time
+}
+
+// client distributes requests round-robin across all healthy endpoints and
+// fails over to the next endpoint if a request fails.
+type client struct {
+	config     ClientConfig
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	endpoints []*endpoint
+	next      int
+}
+
+func newClient(urls []string, config ClientConfig) (*client, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no endpoint specified")
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = len(urls) * 2
+	}
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 3
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = time.Minute
+	}
+	if config.RetryDelay <= 0 {
+		config.RetryDelay = 500 * time.Millisecond
+	}
+	if config.MaxRetryDelay <= 0 {
+		config.MaxRetryDelay = 10 * time.Second
+	}
+
+	endpoints := make([]*endpoint, 0, len(urls))
+	for _, url := range urls {
+		endpoints = append(endpoints, &endpoint{url: strings.TrimSuffix(url, "/")})
+	}
+
+	return &client{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		endpoints:  endpoints,
+	}, nil
+}
+
+// get requests the path from the next available endpoint. Network errors and
+// server errors count as endpoint failures and the request is retried on the
+// next endpoint with a jittered exponential backoff until the retry budget is used up.
+func (c *client) get(ctx context.Context, path string) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt < c.config.MaxRetries; attempt++ {
+		e, available := c.pick()
+
+		var delay time.Duration
+		if attempt > 0 {
+			delay = c.retryDelay(attempt)
+		}
+		// All circuit breakers are open, wait until the first endpoint may be tried again
+		if wait := time.Until(available); wait > delay {
+			delay = wait
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+path, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		response, err := c.httpClient.Do(request)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("request to %s failed: %w", e.url, err)
+			c.reportFailure(e)
+			continue
+		}
+
+		if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+			_ = response.Body.Close()
+			lastErr = fmt.Errorf("request to %s failed: invalid status code: %d", e.url, response.StatusCode)
+			c.reportFailure(e)
+			continue
+		}
+
+		c.reportSuccess(e)
+		return response, nil
+	}
+
+	return nil, fmt.Errorf("all %d attempts failed: %w", c.config.MaxRetries, lastErr)
+}
+
+// pick returns the next endpoint with a closed circuit breaker. If all
+// breakers are open the endpoint which is available again first is returned
+// together with the time it can be tried again.
+func (c *client) pick() (*endpoint, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for i := 0; i < len(c.endpoints); i++ {
+		e := c.endpoints[(c.next+i)%len(c.endpoints)]
+		if !now.Before(e.openUntil) {
+			c.next = (c.next + i + 1) % len(c.endpoints)
+			return e, now
+		}
+	}
+
+	earliest := c.endpoints[0]
+	for _, e := range c.endpoints[1:] {
+		if e.openUntil.Before(earliest.openUntil) {
+			earliest = e
+		}
+	}
+	return earliest, earliest.openUntil
+}
+
+// retryDelay returns the backoff before the given attempt, with a random jitter
+// of up to half the delay so that concurrent requests don't retry in lockstep.
+func (c *client) retryDelay(attempt int) time.Duration {
+	delay := c.config.RetryDelay
+	for i := 1; i < attempt && delay < c.config.MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.config.MaxRetryDelay {
+		delay = c.config.MaxRetryDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (c *client) reportFailure(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.failures++
+	if e.failures >= c.config.FailureThreshold {
+		e.openUntil = time.Now().Add(c.config.Cooldown)
+		logger.Warn().Str("endpoint", e.url).Int("failures", e.failures).Msg("endpoint unavailable, skipping it")
+	}
+}
+
+// reportUnhealthy skips the endpoint until the next health check, e.g. because the node is still syncing.
+func (c *client) reportUnhealthy(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e.failures < c.config.FailureThreshold {
+		e.failures = c.config.FailureThreshold
+	}
+	e.openUntil = time.Now().Add(c.config.HealthCheckInterval)
+}
+
+func (c *client) reportSuccess(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e.failures = 0
+	e.openUntil = time.Time{}
+}
+
+// runHealthChecks periodically checks all endpoints until the context is done.
+func (c *client) runHealthChecks(ctx context.Context) {
+	ticker := time.NewTicker(c.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, e := range c.endpoints {
+				if err := c.checkHealth(ctx, e); err != nil {
+					logger.Debug().Str("endpoint", e.url).Str("err", err.Error()).Msg("health check failed")
+					c.reportUnhealthy(e)
+				} else {
+					c.reportSuccess(e)
+				}
+			}
+		}
+	}
+}
+
+func (c *client) checkHealth(ctx context.Context, e *endpoint) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+healthCheckPath, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("invalid status code: %d", response.StatusCode)
+	}
+
+	// Nodes which are still catching up serve outdated data
+	var status struct {
+		Syncing bool `json:"syncing"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		return fmt.Errorf("invalid syncing response: %w", err)
+	}
+	if status.Syncing {
+		return errors.New("node is syncing")
+	}
+	return nil
+}
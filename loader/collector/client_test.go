@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingServer answers every request with the given status and counts the requests.
+func countingServer(t *testing.T, status int, body string) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	requests := new(atomic.Int64)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+func newTestClient(t *testing.T, urls []string, config ClientConfig) *client {
+	t.Helper()
+
+	if config.RetryDelay == 0 {
+		config.RetryDelay = time.Millisecond
+	}
+	c, err := newClient(urls, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClientFailover(t *testing.T) {
+	failing, failingRequests := countingServer(t, http.StatusServiceUnavailable, "")
+	healthy, healthyRequests := countingServer(t, http.StatusOK, "ok")
+
+	c := newTestClient(t, []string{failing.URL, healthy.URL}, ClientConfig{MaxRetries: 2})
+
+	response, err := c.get(context.Background(), "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	_ = response.Body.Close()
+
+	if string(body) != "ok" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if failingRequests.Load() != 1 || healthyRequests.Load() != 1 {
+		t.Fatalf("expected one request per endpoint, got %d and %d", failingRequests.Load(), healthyRequests.Load())
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	failing, failingRequests := countingServer(t, http.StatusInternalServerError, "")
+	healthy, healthyRequests := countingServer(t, http.StatusOK, "")
+
+	c := newTestClient(t, []string{failing.URL, healthy.URL}, ClientConfig{
+		MaxRetries:       2,
+		FailureThreshold: 2,
+		Cooldown:         time.Hour,
+	})
+
+	for i := 0; i < 6; i++ {
+		response, err := c.get(context.Background(), "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+	}
+
+	// After two failures the breaker of the failing endpoint is open and it isn't requested anymore
+	if failingRequests.Load() != 2 {
+		t.Fatalf("expected 2 requests to the failing endpoint, got %d", failingRequests.Load())
+	}
+	if healthyRequests.Load() != 6 {
+		t.Fatalf("expected 6 requests to the healthy endpoint, got %d", healthyRequests.Load())
+	}
+}
+
+func TestClientWaitsForOpenBreakers(t *testing.T) {
+	failing, requests := countingServer(t, http.StatusInternalServerError, "")
+
+	cooldown := 100 * time.Millisecond
+	c := newTestClient(t, []string{failing.URL}, ClientConfig{
+		MaxRetries:       3,
+		FailureThreshold: 1,
+		Cooldown:         cooldown,
+	})
+
+	start := time.Now()
+	if _, err := c.get(context.Background(), "/"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if requests.Load() != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests.Load())
+	}
+	// Each retry waits for the cooldown of the only endpoint instead of retrying immediately
+	if elapsed := time.Since(start); elapsed < 2*cooldown {
+		t.Fatalf("retries didn't wait for the cooldown, took %s", elapsed)
+	}
+}
+
+func TestClientBackoffContextCanceled(t *testing.T) {
+	failing, _ := countingServer(t, http.StatusInternalServerError, "")
+
+	c := newTestClient(t, []string{failing.URL}, ClientConfig{
+		MaxRetries: 3,
+		RetryDelay: time.Hour,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.get(ctx, "/"); err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestClientRetryDelay(t *testing.T) {
+	c := newTestClient(t, []string{"http://localhost"}, ClientConfig{
+		RetryDelay:    100 * time.Millisecond,
+		MaxRetryDelay: time.Second,
+	})
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 2, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 10, min: 500 * time.Millisecond, max: time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if delay := c.retryDelay(tt.attempt); delay < tt.min || delay > tt.max {
+				t.Fatalf("attempt %d: delay %s not in [%s, %s]", tt.attempt, delay, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestClientHealthCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		healthy bool
+	}{
+		{name: "synced", status: http.StatusOK, body: `{"syncing":false}`, healthy: true},
+		{name: "syncing", status: http.StatusOK, body: `{"syncing":true}`, healthy: false},
+		{name: "invalid response", status: http.StatusOK, body: `<html>`, healthy: false},
+		{name: "server error", status: http.StatusInternalServerError, body: "", healthy: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := countingServer(t, tt.status, tt.body)
+			c := newTestClient(t, []string{server.URL}, ClientConfig{})
+
+			err := c.checkHealth(context.Background(), c.endpoints[0])
+			if (err == nil) != tt.healthy {
+				t.Fatalf("expected healthy=%v, got %v", tt.healthy, err)
+			}
+		})
+	}
+}
+
+func TestClientSkipsUnhealthyEndpoint(t *testing.T) {
+	syncing, syncingRequests := countingServer(t, http.StatusOK, `{"syncing":true}`)
+	synced, _ := countingServer(t, http.StatusOK, `{"syncing":false}`)
+
+	c := newTestClient(t, []string{syncing.URL, synced.URL}, ClientConfig{HealthCheckInterval: time.Hour})
+	for _, e := range c.endpoints {
+		if err := c.checkHealth(context.Background(), e); err != nil {
+			c.reportUnhealthy(e)
+		}
+	}
+	syncingRequests.Store(0)
+
+	for i := 0; i < 4; i++ {
+		response, err := c.get(context.Background(), "/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = response.Body.Close()
+	}
+	if syncingRequests.Load() != 0 {
+		t.Fatalf("expected no requests to the syncing endpoint, got %d", syncingRequests.Load())
+	}
+}
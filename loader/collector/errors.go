@@ -0,0 +1,54 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDone is returned by the BundleIterator once the last bundle was returned.
+	ErrDone = errors.New("reached last bundle")
+
+	// ErrNoBundles is returned if the first page does not contain any bundles.
+	ErrNoBundles = errors.New("could not find any bundles yet; from-bundle-id too high or cron interval too short")
+)
+
+// RetryableError is a temporary failure, the same request can be tried again.
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string {
+	return fmt.Sprintf("retryable: %s", e.Err.Error())
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// FatalError is a permanent failure, retrying the request will not succeed.
+type FatalError struct {
+	Err error
+}
+
+func (e *FatalError) Error() string {
+	return fmt.Sprintf("fatal: %s", e.Err.Error())
+}
+
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether the request that caused the error can be retried.
+func IsRetryable(err error) bool {
+	var retryableError *RetryableError
+	return errors.As(err, &retryableError)
+}
+
+func retryable(format string, a ...any) error {
+	return &RetryableError{Err: fmt.Errorf(format, a...)}
+}
+
+func fatal(format string, a ...any) error {
+	return &FatalError{Err: fmt.Errorf(format, a...)}
+}
@@ -0,0 +1,194 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Pool is the metadata of a KYVE pool.
+type Pool struct {
+	Id                string
+	Name              string
+	Runtime           string
+	Status            string
+	Disabled          bool
+	CurrentKey        string
+	TotalBundles      int64
+	StorageProviderId int64
+	CompressionId     int64
+	// Target time between two bundles in seconds
+	UploadInterval int64
+	// Uncompressed size of the bundle which is currently proposed
+	ProposalDataSize int64
+}
+
+type poolEntry struct {
+	Id   json.Number `json:"id"`
+	Data struct {
+		Name                     string      `json:"name"`
+		Runtime                  string      `json:"runtime"`
+		Disabled                 bool        `json:"disabled"`
+		CurrentKey               string      `json:"current_key"`
+		TotalBundles             json.Number `json:"total_bundles"`
+		CurrentStorageProviderId json.Number `json:"current_storage_provider_id"`
+		CurrentCompressionId     json.Number `json:"current_compression_id"`
+		UploadInterval           json.Number `json:"upload_interval"`
+	} `json:"data"`
+	BundleProposal struct {
+		DataSize json.Number `json:"data_size"`
+	} `json:"bundle_proposal"`
+	Status string `json:"status"`
+}
+
+type poolResponse struct {
+	Pool poolEntry `json:"pool"`
+}
+
+type poolsResponse struct {
+	Pools      []poolEntry `json:"pools"`
+	Pagination struct {
+		NextKey string `json:"next_key"`
+	} `json:"pagination"`
+}
+
+func (e poolEntry) toPool() (*Pool, error) {
+	pool := &Pool{
+		Id:         e.Id.String(),
+		Name:       e.Data.Name,
+		Runtime:    e.Data.Runtime,
+		Status:     e.Status,
+		Disabled:   e.Data.Disabled,
+		CurrentKey: e.Data.CurrentKey,
+	}
+	for _, field := range []struct {
+		value  json.Number
+		target *int64
+	}{
+		{e.Data.TotalBundles, &pool.TotalBundles},
+		{e.Data.CurrentStorageProviderId, &pool.StorageProviderId},
+		{e.Data.CurrentCompressionId, &pool.CompressionId},
+		{e.Data.UploadInterval, &pool.UploadInterval},
+		{e.BundleProposal.DataSize, &pool.ProposalDataSize},
+	} {
+		if field.value == "" {
+			continue
+		}
+		value, err := strconv.ParseInt(field.value.String(), 10, 64)
+		if err != nil {
+			return nil, fatal("invalid metadata of pool %s: %s", pool.Id, err.Error())
+		}
+		*field.target = value
+	}
+	return pool, nil
+}
+
+// ErrPoolNotFound is returned if the endpoint does not know the pool.
+var ErrPoolNotFound = errors.New("pool not found")
+
+// Pool queries the metadata of the pool from the KYVE API.
+func (s Source) Pool(ctx context.Context) (*Pool, error) {
+	if s.archive != nil {
+		return nil, fatal("pool metadata is not available for offline sources")
+	}
+
+	response, err := s.client.get(ctx, fmt.Sprintf("/kyve/query/v1beta1/pool/%d", s.poolId))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, retryable("pool request failed: %s", err.Error())
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, retryable("reading response body failed: %s", err.Error())
+	}
+	if response.StatusCode == http.StatusNotFound {
+		return nil, &FatalError{Err: ErrPoolNotFound}
+	}
+	if response.StatusCode != http.StatusOK {
+		if response.StatusCode == http.StatusTooManyRequests {
+			return nil, retryable("invalid status code: %d", response.StatusCode)
+		}
+		return nil, fatal("invalid status code: %d: %s", response.StatusCode, body)
+	}
+
+	var data poolResponse
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fatal("parsing JSON failed: %s", err.Error())
+	}
+	// Some API versions answer unknown pools with an empty pool instead of a 404
+	if data.Pool.Id == "" {
+		return nil, &FatalError{Err: ErrPoolNotFound}
+	}
+
+	return data.Pool.toPool()
+}
+
+// ListPools returns all pools of the KYVE API.
+func ListPools(ctx context.Context, endpoints []string, config ClientConfig) ([]Pool, error) {
+	c, err := newClient(endpoints, config)
+	if err != nil {
+		return nil, err
+	}
+
+	var pools []Pool
+	query := url.Values{}
+	query.Set("pagination.limit", "100")
+	for {
+		response, err := c.get(ctx, "/kyve/query/v1beta1/pools?"+query.Encode())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, retryable("pools request failed: %s", err.Error())
+		}
+
+		var data poolsResponse
+		err = json.NewDecoder(response.Body).Decode(&data)
+		_ = response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fatal("invalid status code: %d", response.StatusCode)
+		}
+		if err != nil {
+			return nil, fatal("parsing JSON failed: %s", err.Error())
+		}
+
+		for _, entry := range data.Pools {
+			pool, err := entry.toPool()
+			if err != nil {
+				return nil, err
+			}
+			pools = append(pools, *pool)
+		}
+
+		if data.Pagination.NextKey == "" {
+			return pools, nil
+		}
+		query.Set("pagination.key", data.Pagination.NextKey)
+	}
+}
+
+// LatestBundles returns the most recent finalized bundles of the pool, newest first.
+func (s Source) LatestBundles(ctx context.Context, limit int64) ([]Bundle, error) {
+	if s.archive != nil {
+		return nil, fatal("latest bundles are not available for offline sources")
+	}
+
+	query := url.Values{}
+	query.Set("pagination.limit", strconv.FormatInt(limit, 10))
+	query.Set("pagination.reverse", "true")
+
+	page, err := s.requestBundles(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return page.FinalizedBundles, nil
+}
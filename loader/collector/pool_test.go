@@ -0,0 +1,85 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPool(t *testing.T) {
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/kyve/query/v1beta1/pool/1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"pool":{"id":"1","data":{"id":"1","name":"Osmosis","runtime":"@kyvejs/tendermint",
+			"current_key":"12345","total_bundles":"4242","upload_interval":"60","current_storage_provider_id":2,"current_compression_id":1,"disabled":false},
+			"bundle_proposal":{"data_size":"1024"},"status":"POOL_STATUS_ACTIVE"}}`))
+	}), math.MaxInt64)
+
+	pool, err := source.Pool(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Pool{
+		Id:                "1",
+		Name:              "Osmosis",
+		Runtime:           "@kyvejs/tendermint",
+		Status:            "POOL_STATUS_ACTIVE",
+		CurrentKey:        "12345",
+		TotalBundles:      4242,
+		UploadInterval:    60,
+		ProposalDataSize:  1024,
+		StorageProviderId: 2,
+		CompressionId:     1,
+	}
+	if *pool != expected {
+		t.Fatalf("expected %+v, got %+v", expected, *pool)
+	}
+}
+
+func TestPoolNotFound(t *testing.T) {
+	source := newTestSource(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}), math.MaxInt64)
+
+	if _, err := source.Pool(context.Background()); !errors.Is(err, ErrPoolNotFound) {
+		t.Fatalf("expected ErrPoolNotFound, got %v", err)
+	}
+}
+
+func TestListPools(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/kyve/query/v1beta1/pools" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.URL.Query().Get("pagination.key") {
+		case "":
+			_, _ = w.Write([]byte(`{"pools":[{"id":"0","data":{"name":"Cosmos Hub"}},{"id":"1","data":{"name":"Osmosis"}}],"pagination":{"next_key":"next"}}`))
+		case "next":
+			_, _ = w.Write([]byte(`{"pools":[{"id":"2","data":{"name":"Archway"}}],"pagination":{"next_key":null}}`))
+		default:
+			t.Errorf("unexpected pagination key %q", r.URL.Query().Get("pagination.key"))
+		}
+	}))
+	defer server.Close()
+
+	pools, err := ListPools(context.Background(), []string{server.URL}, ClientConfig{MaxRetries: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, pool := range pools {
+		names = append(names, pool.Name)
+	}
+	if fmt.Sprint(names) != "[Cosmos Hub Osmosis Archway]" {
+		t.Fatalf("unexpected pools %v", names)
+	}
+}
@@ -0,0 +1,236 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KeyRange selects items by their key, empty bounds are unbounded.
+type KeyRange struct {
+	FromKey string
+	ToKey   string
+}
+
+// Contains reports whether the key lies within the range, both bounds are inclusive.
+func (r KeyRange) Contains(key string) bool {
+	if r.FromKey != "" && CompareKeys(key, r.FromKey) < 0 {
+		return false
+	}
+	if r.ToKey != "" && CompareKeys(key, r.ToKey) > 0 {
+		return false
+	}
+	return true
+}
+
+// CompareKeys compares keys numerically if both are integers, e.g. block
+// heights, otherwise lexicographically, which covers RFC3339 timestamps.
+func CompareKeys(a, b string) int {
+	x, okA := new(big.Int).SetString(a, 10)
+	y, okB := new(big.Int).SetString(b, 10)
+	if okA && okB {
+		return x.Cmp(y)
+	}
+	return strings.Compare(a, b)
+}
+
+// FirstBundleFromKey returns the id of the first bundle which contains keys >= key.
+func (s Source) FirstBundleFromKey(ctx context.Context, key string) (int64, error) {
+	id, found, err := s.searchBundles(ctx, func(b Bundle) bool {
+		return CompareKeys(b.ToKey, key) >= 0
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("no bundle contains key %s or higher yet", key)
+	}
+	return id, nil
+}
+
+// LastBundleToKey returns the id of the last bundle which contains keys <= key.
+func (s Source) LastBundleToKey(ctx context.Context, key string) (int64, error) {
+	return s.lastBundleBefore(ctx, func(b Bundle) bool {
+		return CompareKeys(b.FromKey, key) > 0
+	}, fmt.Sprintf("key %s", key))
+}
+
+// FirstBundleFromTime returns the id of the first bundle finalized at or after t.
+func (s Source) FirstBundleFromTime(ctx context.Context, t time.Time) (int64, error) {
+	id, found, err := s.searchBundles(ctx, func(b Bundle) bool {
+		return !b.FinalizedAt.Timestamp.Before(t)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("no bundle was finalized at or after %s yet", t.Format(time.RFC3339))
+	}
+	return id, nil
+}
+
+// LastBundleToTime returns the id of the last bundle finalized at or before t.
+func (s Source) LastBundleToTime(ctx context.Context, t time.Time) (int64, error) {
+	return s.lastBundleBefore(ctx, func(b Bundle) bool {
+		return b.FinalizedAt.Timestamp.After(t)
+	}, t.Format(time.RFC3339))
+}
+
+// lastBundleBefore returns the id before the first bundle matching after. If no
+// bundle matches, the latest bundle is returned.
+func (s Source) lastBundleBefore(ctx context.Context, after func(b Bundle) bool, bound string) (int64, error) {
+	first, err := s.bundleAt(ctx, 0, false)
+	if err != nil {
+		return 0, err
+	}
+
+	id, found, err := s.searchBundles(ctx, after)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		latest, err := s.bundleAt(ctx, 0, true)
+		if err != nil {
+			return 0, err
+		}
+		return parseBundleId(*latest)
+	}
+
+	firstId, err := parseBundleId(*first)
+	if err != nil {
+		return 0, err
+	}
+	if id <= firstId {
+		return 0, fmt.Errorf("no bundle before %s", bound)
+	}
+	return id - 1, nil
+}
+
+// searchBundles returns the smallest bundle id for which pred is true with a binary search
+// over all bundles of the pool. pred has to be monotonic in the bundle id.
+func (s Source) searchBundles(ctx context.Context, pred func(b Bundle) bool) (int64, bool, error) {
+	first, err := s.bundleAt(ctx, 0, false)
+	if err != nil {
+		return 0, false, err
+	}
+	latest, err := s.bundleAt(ctx, 0, true)
+	if err != nil {
+		return 0, false, err
+	}
+
+	firstId, err := parseBundleId(*first)
+	if err != nil {
+		return 0, false, err
+	}
+	latestId, err := parseBundleId(*latest)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if !pred(*latest) {
+		return 0, false, nil
+	}
+
+	// Bundle ids can have gaps in archives, therefore the probed bundle is the first one >= mid
+	result := latestId
+	lo, hi := firstId, latestId-1
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+
+		bundle, err := s.bundleAt(ctx, mid, false)
+		if err != nil {
+			return 0, false, err
+		}
+		id, err := parseBundleId(*bundle)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if id > hi {
+			hi = mid - 1
+			continue
+		}
+		if pred(*bundle) {
+			result = id
+			hi = mid - 1
+		} else {
+			lo = id + 1
+		}
+	}
+
+	return result, true, nil
+}
+
+// Bundle returns the metadata of a single finalized bundle.
+func (s Source) Bundle(ctx context.Context, id int64) (*Bundle, error) {
+	bundle, err := s.bundleAt(ctx, id, false)
+	if err != nil {
+		if errors.Is(err, ErrNoBundles) {
+			return nil, fatal("bundle %d not found", id)
+		}
+		return nil, err
+	}
+
+	bundleId, err := parseBundleId(*bundle)
+	if err != nil {
+		return nil, err
+	}
+	if bundleId != id {
+		return nil, fatal("bundle %d not found", id)
+	}
+	return bundle, nil
+}
+
+// bundleAt returns the first bundle with an id >= offset or the latest bundle if reverse is set.
+// Retryable errors are retried until ctx is done.
+func (s Source) bundleAt(ctx context.Context, offset int64, reverse bool) (*Bundle, error) {
+	for {
+		page, err := s.fetchSingle(ctx, offset, reverse)
+		if err != nil {
+			if IsRetryable(err) && ctx.Err() == nil {
+				logger.Error().Str("err", err.Error()).Msg("failed to fetch bundle, retry in 5 seconds")
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(5 * time.Second):
+				}
+				continue
+			}
+			return nil, err
+		}
+
+		if len(page.FinalizedBundles) == 0 {
+			return nil, ErrNoBundles
+		}
+		return &page.FinalizedBundles[0], nil
+	}
+}
+
+func (s Source) fetchSingle(ctx context.Context, offset int64, reverse bool) (*Response, error) {
+	if s.archive != nil {
+		return s.archive.single(offset, reverse), nil
+	}
+
+	query := url.Values{}
+	query.Set("pagination.limit", "1")
+	if reverse {
+		query.Set("pagination.reverse", "true")
+	} else {
+		query.Set("pagination.offset", strconv.FormatInt(offset, 10))
+	}
+
+	return s.requestBundles(ctx, query)
+}
+
+func parseBundleId(b Bundle) (int64, error) {
+	id, err := strconv.ParseInt(b.Id, 10, 64)
+	if err != nil {
+		return 0, fatal("malformed bundle response, invalid bundle-id: %s", err.Error())
+	}
+	return id, nil
+}
@@ -0,0 +1,150 @@
+package collector
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var baseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newRangeTestSource creates an archive source with bundles of ten keys each,
+// bundle i contains the keys 10*i+1 to 10*i+10 and was finalized after i hours.
+func newRangeTestSource(t *testing.T, ids []int64) Source {
+	t.Helper()
+
+	bundles := make([]Bundle, 0, len(ids))
+	for _, id := range ids {
+		b := Bundle{
+			PoolId:  "1",
+			Id:      strconv.FormatInt(id, 10),
+			FromKey: strconv.FormatInt(10*id+1, 10),
+			ToKey:   strconv.FormatInt(10*id+10, 10),
+		}
+		b.FinalizedAt.Timestamp = baseTime.Add(time.Duration(id) * time.Hour)
+		bundles = append(bundles, b)
+	}
+	return newArchiveTestSource(t, bundles, SourceConfig{})
+}
+
+// newArchiveTestSource returns a source of pool 1 which reads the bundles from an archive.
+func newArchiveTestSource(t *testing.T, bundles []Bundle, config SourceConfig) Source {
+	t.Helper()
+
+	dir := t.TempDir()
+	writer, err := NewArchiveWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WritePage(bundles); err != nil {
+		t.Fatal(err)
+	}
+
+	config.Archive, err = OpenArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(config.Archive.Close)
+
+	config.PoolId, config.ToBundleId, config.BatchSize = 1, 1000, 10
+	source, err := NewSource(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "9", b: "10", want: -1},
+		{a: "100", b: "100", want: 0},
+		{a: "18446744073709551616", b: "9", want: 1},
+		{a: "2024-01-02T00:00:00Z", b: "2024-01-10T00:00:00Z", want: -1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareKeys(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareKeys(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestKeyRangeContains(t *testing.T) {
+	r := KeyRange{FromKey: "15", ToKey: "20"}
+	for key, want := range map[string]bool{"14": false, "15": true, "20": true, "21": false, "150": false} {
+		if got := r.Contains(key); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", key, got, want)
+		}
+	}
+
+	if !(KeyRange{}).Contains("1") {
+		t.Error("empty range should contain all keys")
+	}
+}
+
+func TestBundleSearchByKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     []int64
+		fromKey string
+		toKey   string
+		from    int64
+		to      int64
+	}{
+		{name: "within bundles", ids: []int64{0, 1, 2, 3, 4, 5, 6, 7}, fromKey: "25", toKey: "55", from: 2, to: 5},
+		{name: "bundle edges", ids: []int64{0, 1, 2, 3, 4, 5, 6, 7}, fromKey: "21", toKey: "60", from: 2, to: 5},
+		{name: "first and last bundle", ids: []int64{0, 1, 2, 3}, fromKey: "1", toKey: "1000", from: 0, to: 3},
+		{name: "archive with gaps", ids: []int64{3, 4, 8, 9, 12}, fromKey: "51", toKey: "95", from: 8, to: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := newRangeTestSource(t, tt.ids)
+
+			from, err := source.FirstBundleFromKey(context.Background(), tt.fromKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			to, err := source.LastBundleToKey(context.Background(), tt.toKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if from != tt.from || to != tt.to {
+				t.Fatalf("got bundles %d-%d, want %d-%d", from, to, tt.from, tt.to)
+			}
+		})
+	}
+}
+
+func TestBundleSearchOutOfRange(t *testing.T) {
+	source := newRangeTestSource(t, []int64{0, 1, 2})
+
+	if _, err := source.FirstBundleFromKey(context.Background(), "31"); err == nil {
+		t.Error("expected error for key after the latest bundle")
+	}
+	if _, err := source.LastBundleToKey(context.Background(), "0"); err == nil {
+		t.Error("expected error for key before the first bundle")
+	}
+}
+
+func TestBundleSearchByTime(t *testing.T) {
+	source := newRangeTestSource(t, []int64{0, 1, 2, 3, 4, 5})
+
+	from, err := source.FirstBundleFromTime(context.Background(), baseTime.Add(90*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := source.LastBundleToTime(context.Background(), baseTime.Add(4*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if from != 2 || to != 4 {
+		t.Fatalf("got bundles %d-%d, want 2-4", from, to)
+	}
+}
@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type PolicyAction string
+
+const (
+	// PolicyHold stops at the first violating bundle, so that no later bundle is loaded
+	PolicyHold PolicyAction = "hold"
+	// PolicyQuarantine skips violating bundles and records them in the quarantine file
+	PolicyQuarantine PolicyAction = "quarantine"
+)
+
+// TrustPolicy defines which bundles of a source are trusted enough to be loaded. Bundles
+// which are not finalized long enough are held back and checked again later.
+type TrustPolicy struct {
+	// Minimum ratio of valid to total vote power, 0 disables the check
+	MinVotePowerRatio float64
+	// If set, only bundles of these uploaders are accepted
+	AllowedUploaders []string
+	DeniedUploaders  []string
+	// Minimum time since the bundle was finalized
+	MinFinalizationAge time.Duration
+
+	Action PolicyAction
+	// JSON lines file quarantined bundles are appended to, optional
+	QuarantineFile string
+
+	// OnViolation is called for every untrusted bundle, held bundles are not reported, optional
+	OnViolation func(bundle Bundle, violation *PolicyViolation)
+
+	mu sync.Mutex
+}
+
+// PolicyViolation describes why a bundle is not trusted.
+type PolicyViolation struct {
+	BundleId string
+	Reason   string
+	// The bundle is not finalized long enough and can be loaded after HoldUntil
+	HoldUntil time.Time
+}
+
+func (v *PolicyViolation) Error() string {
+	return fmt.Sprintf("bundle %s violates trust policy: %s", v.BundleId, v.Reason)
+}
+
+func (p *TrustPolicy) Validate() error {
+	if p.MinVotePowerRatio < 0 || p.MinVotePowerRatio > 1 {
+		return fmt.Errorf("min_vote_power_ratio has to be between 0 and 1")
+	}
+	if p.MinFinalizationAge < 0 {
+		return fmt.Errorf("min_finalization_age can't be negative")
+	}
+	switch p.Action {
+	case "":
+		p.Action = PolicyHold
+	case PolicyHold, PolicyQuarantine:
+	default:
+		return fmt.Errorf("unknown on_violation action %q, expected hold or quarantine", p.Action)
+	}
+	return nil
+}
+
+// Check returns a violation if the bundle is not trusted at the given time.
+func (p *TrustPolicy) Check(bundle Bundle, now time.Time) *PolicyViolation {
+	violation := func(format string, a ...any) *PolicyViolation {
+		return &PolicyViolation{BundleId: bundle.Id, Reason: fmt.Sprintf(format, a...)}
+	}
+
+	if slices.Contains(p.DeniedUploaders, bundle.Uploader) {
+		return violation("uploader %s is denied", bundle.Uploader)
+	}
+	if len(p.AllowedUploaders) > 0 && !slices.Contains(p.AllowedUploaders, bundle.Uploader) {
+		return violation("uploader %s is not allowed", bundle.Uploader)
+	}
+
+	if p.MinVotePowerRatio > 0 {
+		valid, okValid := new(big.Float).SetString(bundle.StakeSecurity.ValidVotePower)
+		total, okTotal := new(big.Float).SetString(bundle.StakeSecurity.TotalVotePower)
+		if !okValid || !okTotal || total.Sign() <= 0 {
+			return violation("missing stake security")
+		}
+		ratio, _ := new(big.Float).Quo(valid, total).Float64()
+		if ratio < p.MinVotePowerRatio {
+			return violation("vote power ratio %.4f is below %.4f", ratio, p.MinVotePowerRatio)
+		}
+	}
+
+	if p.MinFinalizationAge > 0 {
+		holdUntil := bundle.FinalizedAt.Timestamp.Add(p.MinFinalizationAge)
+		if now.Before(holdUntil) {
+			v := violation("finalized less than %s ago", p.MinFinalizationAge)
+			v.HoldUntil = holdUntil
+			return v
+		}
+	}
+
+	return nil
+}
+
+// quarantine records a rejected bundle, so that it can be reviewed and loaded manually.
+func (p *TrustPolicy) quarantine(bundle Bundle, violation *PolicyViolation) error {
+	if p.QuarantineFile == "" {
+		return nil
+	}
+
+	line, err := json.Marshal(struct {
+		Bundle
+		Reason        string    `json:"reason"`
+		QuarantinedAt time.Time `json:"quarantined_at"`
+	}{bundle, violation.Reason, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.QuarantineFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// apply returns the trusted prefix of the bundles. If a bundle has to be held back, the
+// remaining bundles are dropped and the violation is returned together with its index.
+// Untrusted bundles are skipped in quarantine mode, otherwise they are returned as violation.
+func (p *TrustPolicy) apply(bundles []Bundle, now time.Time) ([]Bundle, int, *PolicyViolation, error) {
+	trusted := make([]Bundle, 0, len(bundles))
+	for i, bundle := range bundles {
+		violation := p.Check(bundle, now)
+		if violation == nil {
+			trusted = append(trusted, bundle)
+			continue
+		}
+
+		if violation.HoldUntil.IsZero() && p.OnViolation != nil {
+			p.OnViolation(bundle, violation)
+		}
+
+		if violation.HoldUntil.IsZero() && p.Action == PolicyQuarantine {
+			logger.Warn().Str("bundle_id", bundle.Id).Str("reason", violation.Reason).Msg("quarantined bundle")
+			if err := p.quarantine(bundle, violation); err != nil {
+				return nil, 0, nil, fmt.Errorf("failed to quarantine bundle %s: %w", bundle.Id, err)
+			}
+			continue
+		}
+
+		return trusted, i, violation, nil
+	}
+	return trusted, len(bundles), nil, nil
+}
@@ -0,0 +1,146 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func trustTestBundle(id int64, uploader string, valid, total string, finalizedAt time.Time) Bundle {
+	b := Bundle{PoolId: "1", Id: strconv.FormatInt(id, 10), Uploader: uploader}
+	b.StakeSecurity.ValidVotePower = valid
+	b.StakeSecurity.TotalVotePower = total
+	b.FinalizedAt.Timestamp = finalizedAt
+	return b
+}
+
+func TestTrustPolicyCheck(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	policy := &TrustPolicy{
+		MinVotePowerRatio:  0.67,
+		DeniedUploaders:    []string{"kyve1bad"},
+		MinFinalizationAge: time.Hour,
+	}
+
+	tests := []struct {
+		name   string
+		bundle Bundle
+		reason string
+		held   bool
+	}{
+		{name: "trusted", bundle: trustTestBundle(0, "kyve1good", "70", "100", now.Add(-2*time.Hour))},
+		{name: "denied uploader", bundle: trustTestBundle(0, "kyve1bad", "70", "100", now.Add(-2*time.Hour)), reason: "denied"},
+		{name: "low vote power", bundle: trustTestBundle(0, "kyve1good", "60", "100", now.Add(-2*time.Hour)), reason: "vote power"},
+		{name: "missing stake security", bundle: trustTestBundle(0, "kyve1good", "", "", now.Add(-2*time.Hour)), reason: "stake security"},
+		{name: "too young", bundle: trustTestBundle(0, "kyve1good", "70", "100", now.Add(-time.Minute)), reason: "finalized", held: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation := policy.Check(tt.bundle, now)
+			if tt.reason == "" {
+				if violation != nil {
+					t.Fatalf("unexpected violation: %v", violation)
+				}
+				return
+			}
+
+			if violation == nil || !strings.Contains(violation.Reason, tt.reason) {
+				t.Fatalf("expected violation containing %q, got %v", tt.reason, violation)
+			}
+			if !violation.HoldUntil.IsZero() != tt.held {
+				t.Fatalf("expected held=%v, got %v", tt.held, violation.HoldUntil)
+			}
+		})
+	}
+
+	allowList := &TrustPolicy{AllowedUploaders: []string{"kyve1good"}}
+	if allowList.Check(trustTestBundle(0, "kyve1other", "", "", now), now) == nil {
+		t.Fatal("expected uploader which is not allowed to be rejected")
+	}
+}
+
+func newTrustTestSource(t *testing.T, bundles []Bundle, policy *TrustPolicy) Source {
+	t.Helper()
+
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return newArchiveTestSource(t, bundles, SourceConfig{TrustPolicy: policy})
+}
+
+func TestBundleIteratorTrustPolicyQuarantine(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	quarantineFile := filepath.Join(t.TempDir(), "quarantine.jsonl")
+
+	var violations int
+	source := newTrustTestSource(t, []Bundle{
+		trustTestBundle(0, "kyve1good", "1", "1", old),
+		trustTestBundle(1, "kyve1bad", "1", "1", old),
+		trustTestBundle(2, "kyve1good", "1", "1", old),
+	}, &TrustPolicy{
+		DeniedUploaders: []string{"kyve1bad"},
+		Action:          PolicyQuarantine,
+		QuarantineFile:  quarantineFile,
+		OnViolation:     func(Bundle, *PolicyViolation) { violations++ },
+	})
+
+	ids, err := collectAll(t, source.Bundles(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIds(t, ids, []int64{0, 2})
+
+	data, err := os.ReadFile(quarantineFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations != 1 || strings.Count(string(data), "\n") != 1 || !strings.Contains(string(data), `"id":"1"`) {
+		t.Fatalf("expected bundle 1 to be quarantined once, got %d violations and %s", violations, data)
+	}
+}
+
+func TestBundleIteratorTrustPolicyHold(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	source := newTrustTestSource(t, []Bundle{
+		trustTestBundle(0, "kyve1good", "1", "1", old),
+		trustTestBundle(1, "kyve1bad", "1", "1", old),
+		trustTestBundle(2, "kyve1good", "1", "1", old),
+	}, &TrustPolicy{DeniedUploaders: []string{"kyve1bad"}})
+
+	ids, err := collectAll(t, source.Bundles(0))
+	assertIds(t, ids, []int64{0})
+
+	var violation *PolicyViolation
+	if !errors.As(err, &violation) || violation.BundleId != "1" || IsRetryable(err) {
+		t.Fatalf("expected fatal policy violation of bundle 1, got %v", err)
+	}
+}
+
+func TestBundleIteratorTrustPolicyFinalizationAge(t *testing.T) {
+	now := time.Now()
+	source := newTrustTestSource(t, []Bundle{
+		trustTestBundle(0, "kyve1good", "1", "1", now.Add(-time.Hour)),
+		trustTestBundle(1, "kyve1good", "1", "1", now.Add(-time.Minute+100*time.Millisecond)),
+	}, &TrustPolicy{MinFinalizationAge: time.Minute})
+
+	it := source.Bundles(0)
+	bundles, err := it.Next(context.Background())
+	if err != nil || len(bundles) != 1 || bundles[0].Id != "0" {
+		t.Fatalf("expected only bundle 0, got %v, %v", bundles, err)
+	}
+
+	start := time.Now()
+	bundles, err = it.Next(context.Background())
+	if err != nil || len(bundles) != 1 || bundles[0].Id != "1" {
+		t.Fatalf("expected bundle 1 after the hold, got %v, %v", bundles, err)
+	}
+	if time.Since(start) < 50*time.Millisecond {
+		t.Fatal("expected the iterator to wait until the bundle was finalized long enough")
+	}
+}
@@ -11,7 +11,21 @@ type SourceConfig struct {
 
 	BatchSize int64
 
-	Endpoint string
+	// REST endpoints of the KYVE chain, requests are distributed across all of them
+	Endpoints []string
+	Client    ClientConfig
+
+	// Archive replaces the KYVE API with a local copy of the pool
+	Archive *Archive
+
+	// KeyRange trims the items of the edge bundles to the selected keys
+	KeyRange KeyRange
+
+	// Follow keeps polling for new bundles after the latest bundle was reached
+	Follow *FollowConfig
+
+	// TrustPolicy filters the bundles before they are loaded, optional
+	TrustPolicy *TrustPolicy
 
 	PartialSync bool
 
@@ -27,7 +41,11 @@ type Source struct {
 
 	batchSize int64
 
-	endpoint string
+	follow      *FollowConfig
+	trustPolicy *TrustPolicy
+
+	client  *client
+	archive *Archive
 }
 
 type Bundle struct {
@@ -60,3 +78,10 @@ type Response struct {
 		Total   string `json:"total"`
 	} `json:"pagination"`
 }
+
+type FollowConfig struct {
+	// Delay before polling again after the latest bundle was reached
+	PollInterval time.Duration
+	// The delay doubles with every poll without new bundles up to MaxPollInterval
+	MaxPollInterval time.Duration
+}
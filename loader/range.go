@@ -0,0 +1,82 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+)
+
+// RangeSelection selects the data to load by item keys (e.g. block heights) or by the
+// finalization time of the bundles instead of bundle ids. Empty values are unbounded.
+type RangeSelection struct {
+	FromKey  string
+	ToKey    string
+	FromTime time.Time
+	ToTime   time.Time
+}
+
+func (r RangeSelection) IsSet() bool {
+	return r.FromKey != "" || r.ToKey != "" || !r.FromTime.IsZero() || !r.ToTime.IsZero()
+}
+
+// resolveRange narrows the bundle range of the source to the bundles covering the selection.
+// Items outside the selected keys are trimmed from the edge bundles during the conversion.
+func resolveRange(ctx context.Context, sourceConfig *collector.SourceConfig, selection RangeSelection) error {
+	searchConfig := *sourceConfig
+	searchConfig.FromBundleId, searchConfig.ToBundleId = 0, math.MaxInt64
+
+	source, err := collector.NewSource(searchConfig)
+	if err != nil {
+		return err
+	}
+
+	if selection.FromKey != "" {
+		id, err := source.FirstBundleFromKey(ctx, selection.FromKey)
+		if err != nil {
+			return err
+		}
+		sourceConfig.FromBundleId = max(sourceConfig.FromBundleId, id)
+	}
+	if !selection.FromTime.IsZero() {
+		id, err := source.FirstBundleFromTime(ctx, selection.FromTime)
+		if err != nil {
+			return err
+		}
+		sourceConfig.FromBundleId = max(sourceConfig.FromBundleId, id)
+	}
+	if selection.ToKey != "" {
+		id, err := source.LastBundleToKey(ctx, selection.ToKey)
+		if err != nil {
+			return err
+		}
+		sourceConfig.ToBundleId = min(sourceConfig.ToBundleId, id)
+		sourceConfig.PartialSync = true
+	}
+	if !selection.ToTime.IsZero() {
+		id, err := source.LastBundleToTime(ctx, selection.ToTime)
+		if err != nil {
+			return err
+		}
+		sourceConfig.ToBundleId = min(sourceConfig.ToBundleId, id)
+		sourceConfig.PartialSync = true
+	}
+
+	if sourceConfig.FromBundleId > sourceConfig.ToBundleId {
+		return fmt.Errorf("selection does not contain any bundles")
+	}
+
+	sourceConfig.KeyRange = collector.KeyRange{
+		FromKey: selection.FromKey,
+		ToKey:   selection.ToKey,
+	}
+
+	logger.Info().
+		Int64("from_bundle_id", sourceConfig.FromBundleId).
+		Int64("to_bundle_id", sourceConfig.ToBundleId).
+		Msg("resolved bundle range")
+
+	return nil
+}
@@ -2,6 +2,7 @@ package loader
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/KYVENetwork/KYVE-DLT/destinations"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
@@ -15,10 +16,24 @@ var (
 	logger = utils.DltLogger("loader")
 )
 
-func (loader *Loader) Start(ctx context.Context, y bool, sync bool) {
+const maxPollInterval = 5 * time.Minute
+
+// Start loads the bundles until all bundles are loaded or the context is canceled. An error is
+// returned if a bundle can't be loaded, in that case the bundles which were converted before are
+// still written to the destination.
+func (loader *Loader) Start(ctx context.Context, y bool, sync bool) error {
 	logger.Debug().Msg(fmt.Sprintf("BundleConfig: %#v", loader.sourceConfig))
 	logger.Debug().Msg(fmt.Sprintf("ConcurrencyConfig: %#v", loader.config))
 
+	loader.err = nil
+	loader.aborted, loader.abort = context.WithCancel(context.Background())
+	defer loader.abort()
+
+	// The collector also stops if a worker fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	context.AfterFunc(loader.aborted, cancel)
+
 	loader.bundlesChannel = make(chan BundlesBusItem, loader.config.ChannelSize)
 	loader.destinationChannel = make(chan destinations.DestinationBusItem, loader.config.ChannelSize)
 
@@ -41,11 +56,8 @@ func (loader *Loader) Start(ctx context.Context, y bool, sync bool) {
 	// PartialSync is enabled when --to-bundle-id is set
 	if loader.sourceConfig.PartialSync && !loader.sourceConfig.Force {
 		if loader.sourceConfig.FromBundleId > loader.sourceConfig.ToBundleId {
-			logger.Error().Int64("from", loader.sourceConfig.FromBundleId).
-				Str("connection", loader.ConnectionName).
-				Int64("to", loader.sourceConfig.ToBundleId).
-				Msg("from_bundle_id > to_bundle_id - this step can be skipped with --force")
-			return
+			return fmt.Errorf("from_bundle_id %d > to_bundle_id %d - this step can be skipped with --force",
+				loader.sourceConfig.FromBundleId, loader.sourceConfig.ToBundleId)
 		}
 	}
 
@@ -53,12 +65,12 @@ func (loader *Loader) Start(ctx context.Context, y bool, sync bool) {
 		if !loader.sourceConfig.PartialSync {
 			if !utils.PromptConfirm(fmt.Sprintf("\u001B[36m[DLT]\u001B[0m Should data from bundle_id %d be loaded until all bundles are synced?\n\u001B[36m[y/N]\u001B[0m: ", loader.sourceConfig.FromBundleId)) {
 				logger.Error().Msg("aborted")
-				return
+				return nil
 			}
 		} else {
 			if !utils.PromptConfirm(fmt.Sprintf("\u001B[36m[DLT]\u001B[0m Should data from bundle_id %d to %d be loaded?\n\u001B[36m[y/N]\u001B[0m: ", loader.sourceConfig.FromBundleId, loader.sourceConfig.ToBundleId)) {
 				logger.Error().Msg("aborted")
-				return
+				return nil
 			}
 		}
 	}
@@ -71,7 +83,7 @@ func (loader *Loader) Start(ctx context.Context, y bool, sync bool) {
 		CSVWorkerCount: loader.config.CsvWorkerCount,
 		MaxRamGB:       utils.GLOBAL_MAX_RAM_GB,
 		PoolId:         loader.sourceConfig.PoolId,
-		Endpoint:       loader.sourceConfig.Endpoint,
+		Endpoint:       loader.endpoint(),
 		FromBundleId:   loader.sourceConfig.FromBundleId,
 		ToBundleId:     loader.sourceConfig.ToBundleId,
 	}
@@ -102,28 +114,83 @@ func (loader *Loader) Start(ctx context.Context, y bool, sync bool) {
 		UncompressedBytesSynced: loader.statusProperties.uncompressedBytesSynced.Load(),
 		BundlesSynced:           loader.statusProperties.bundlesSynced.Load(),
 	})
+
+	loader.errMutex.Lock()
+	defer loader.errMutex.Unlock()
+	return loader.err
+}
+
+// fail stops the collector and the workers after an error which can't be resolved by retrying.
+// Only the first error is kept.
+func (loader *Loader) fail(err error) {
+	loader.errMutex.Lock()
+	defer loader.errMutex.Unlock()
+
+	if loader.err == nil {
+		loader.err = err
+		loader.abort()
+	}
+}
+
+func (loader *Loader) endpoint() string {
+	if loader.sourceConfig.Archive != nil || len(loader.sourceConfig.Endpoints) == 0 {
+		return "offline"
+	}
+	return loader.sourceConfig.Endpoints[0]
+}
+
+// Follow keeps the pipeline open after the latest bundle was loaded and
+// polls for new bundles until the context of Start is canceled.
+func (loader *Loader) Follow(pollInterval time.Duration) {
+	loader.sourceConfig.Follow = &collector.FollowConfig{
+		PollInterval:    pollInterval,
+		MaxPollInterval: max(pollInterval, maxPollInterval),
+	}
+}
+
+// Close releases the resources of the source.
+func (loader *Loader) Close() {
+	if loader.sourceConfig.Archive != nil {
+		loader.sourceConfig.Archive.Close()
+	}
 }
 
 func (loader *Loader) bundlesCollector(ctx context.Context) {
 	defer close(loader.bundlesChannel)
 
 	fetcher, err := collector.NewSource(loader.sourceConfig)
-
 	if err != nil {
-		panic(err)
+		loader.fail(err)
+		return
 	}
 
+	var sequence int64
 	offset := loader.sourceConfig.FromBundleId
 	logger.Debug().Str("connection", loader.ConnectionName).Int64("bundle_id", offset).Msg("setting offset")
 
 	fetcher.FetchBundles(ctx, offset, loader.ConnectionName, func(bundles []collector.Bundle, err error) {
 		if err != nil {
-			logger.Error().Msg(fmt.Sprintf("error fetching bundles: %v", err))
+			if errors.Is(err, collector.ErrNoBundles) {
+				logger.Info().Str("connection", loader.ConnectionName).Msg(collector.ErrNoBundles.Error())
+				return
+			}
+			if !collector.IsRetryable(err) {
+				loader.fail(fmt.Errorf("error fetching bundles: %w", err))
+				return
+			}
+			logger.Error().Str("connection", loader.ConnectionName).Msg(fmt.Sprintf("error fetching bundles: %v", err))
 			logger.Info().Msg("waiting...")
 			utils.PrometheusSyncStepFailedRetry.WithLabelValues(loader.ConnectionName).Inc()
 			time.Sleep(5 * time.Second)
 		} else {
 			if len(bundles) > 0 {
+				for _, bundle := range bundles {
+					if err := loader.validator.CheckContinuity(bundle); err != nil {
+						loader.fail(fmt.Errorf("bundles are not continuous at bundle %s: %w", bundle.Id, err))
+						return
+					}
+				}
+
 				fromBundleId, _ := strconv.ParseUint(bundles[0].Id, 10, 64)
 				toBundleId, _ := strconv.ParseUint(bundles[len(bundles)-1].Id, 10, 64)
 
@@ -134,16 +201,21 @@ func (loader *Loader) bundlesCollector(ctx context.Context) {
 					Int("amount", len(bundles)).
 					Msg("fetched")
 
+				if loader.aborted.Err() != nil {
+					return
+				}
 				loader.bundlesChannel <- BundlesBusItem{
-					bundles: bundles,
+					bundles:  bundles,
+					sequence: sequence,
 					status: Status{
 						FromBundleId: int64(fromBundleId),
 						ToBundleId:   int64(toBundleId),
 						FromKey:      bundles[0].FromKey,
 						ToKey:        bundles[len(bundles)-1].ToKey,
-						ExtractedAt:  time.Now().Format(time.RFC3339Nano),
+						ExtractedAt:  time.Now().UTC(),
 					},
 				}
+				sequence++
 			}
 		}
 	})
@@ -162,36 +234,71 @@ func (loader *Loader) dataRowWorker(name string) {
 			return
 		}
 
-		utils.AwaitEnoughMemory(name)
+		// Drain the remaining items after a failure, the collector stops sending soon
+		if loader.aborted.Err() != nil {
+			continue
+		}
 
-		items := make([]schema.DataRow, 0)
+		var totalUncompressedSize, totalCompressedSize int64
 
-		totalUncompressedSize := int64(0)
-		totalCompressedSize := int64(0)
+		// The rows are passed to the destination while the bundles are converted, a failed
+		// attempt discards the rows which were passed and converts all bundles of the item again
+		err := utils.TryWithExponentialBackoff(func() error {
+			if err := loader.aborted.Err(); err != nil {
+				return utils.Permanent(err)
+			}
+			totalUncompressedSize, totalCompressedSize = 0, 0
+
+			rows := destinations.NewRowStream(loader.aborted, loader.memory, loader.chunkSize)
+			loader.destinationChannel <- destinations.DestinationBusItem{
+				Rows:         rows,
+				FromBundleId: item.status.FromBundleId,
+				ToBundleId:   item.status.ToBundleId,
+				Sequence:     item.sequence,
+			}
 
-		for _, k := range item.bundles {
-			utils.TryWithExponentialBackoff(func() error {
+			for _, k := range item.bundles {
 				result, err := loader.config.SourceSchema.DownloadAndConvertBundle(k, schema.ExtraData{
 					Name:        name,
 					ExtractedAt: item.status.ExtractedAt,
-				})
+					Archive:     loader.sourceConfig.Archive,
+					KeyRange:    loader.sourceConfig.KeyRange,
+					Reserve: func(compressedSize int64) (*utils.MemoryReservation, error) {
+						estimate := loader.memory.EstimateBundle(compressedSize)
+						return loader.memory.Reserve(loader.aborted, min(estimate, loader.bundleMemoryLimit))
+					},
+				}, rows)
+				if err == nil {
+					err = loader.validator.CheckBundle(k, result.Items)
+					if err != nil {
+						err = utils.Permanent(err)
+					}
+				}
 				if err != nil {
-					return err
+					rows.Abort(err)
+					return fmt.Errorf("bundle %s: %w", k.Id, err)
 				}
-				items = append(items, result.Data...)
+				loader.memory.ObserveBundle(result.CompressedSize, result.UncompressedSize)
 				totalUncompressedSize += result.UncompressedSize
 				totalCompressedSize += result.CompressedSize
-				return nil
-			}, func(err error) {
-				logger.Error().Str("connection", loader.ConnectionName).Msg(fmt.Sprintf("(%s) error: %s \nRetry in 5 seconds.\n", name, err.Error()))
-				utils.PrometheusSyncStepFailedRetry.WithLabelValues(loader.ConnectionName).Inc()
-			})
-		}
-
-		loader.destinationChannel <- destinations.DestinationBusItem{
-			Data:         items,
-			FromBundleId: item.status.FromBundleId,
-			ToBundleId:   item.status.ToBundleId,
+			}
+			return rows.Close()
+		}, func(err error) {
+			logger.Error().Str("connection", loader.ConnectionName).Msg(fmt.Sprintf("(%s) error: %s \nRetry in 5 seconds.\n", name, err.Error()))
+			utils.PrometheusSyncStepFailedRetry.WithLabelValues(loader.ConnectionName).Inc()
+		})
+		if err != nil {
+			if loader.aborted.Err() == nil {
+				logger.Error().
+					Str("connection", loader.ConnectionName).
+					Str("worker-id", name).
+					Int64("fromBundleId", item.status.FromBundleId).
+					Int64("toBundleId", item.status.ToBundleId).
+					Str("err", err.Error()).
+					Msg("failed to process bundles, retrying will not succeed")
+				loader.fail(fmt.Errorf("failed to process bundles %d to %d: %w", item.status.FromBundleId, item.status.ToBundleId, err))
+			}
+			continue
 		}
 
 		utils.PrometheusBundlesSynced.WithLabelValues(loader.ConnectionName).Add(float64(item.status.ToBundleId - item.status.FromBundleId + 1))
@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/destinations"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// testDestination drains the destination channel, the tests fail before any rows are converted.
+type testDestination struct {
+	items chan destinations.DestinationBusItem
+}
+
+func (d *testDestination) Close() {}
+
+func (d *testDestination) GetLatestBundleId() *int64 {
+	return nil
+}
+
+func (d *testDestination) Initialize(_ schema.DataSource, destinationChannel chan destinations.DestinationBusItem) {
+	d.items = destinationChannel
+}
+
+func (d *testDestination) StartProcess(waitGroup *sync.WaitGroup) {
+	waitGroup.Add(1)
+	go func() {
+		defer waitGroup.Done()
+		for range d.items {
+		}
+	}()
+}
+
+// startTestLoader runs Start with the source config and fails the test if it doesn't return in time.
+func startTestLoader(t *testing.T, sourceConfig collector.SourceConfig) error {
+	t.Helper()
+
+	utils.OptOut = true
+	loader := NewLoader(
+		Config{ChannelSize: 1, CsvWorkerCount: 1, SourceSchema: schema.Base{}},
+		sourceConfig, &testDestination{}, "test",
+		StatusProperties{
+			uncompressedBytesSynced: new(atomic.Int64),
+			compressedBytesSynced:   new(atomic.Int64),
+			bundlesSynced:           new(atomic.Int64),
+		},
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := loader.Start(ctx, true, false)
+	if ctx.Err() != nil {
+		t.Fatal("loader did not stop")
+	}
+	return err
+}
+
+func TestStartFailsOnFatalFetchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	err := startTestLoader(t, collector.SourceConfig{PoolId: 1, ToBundleId: 100, BatchSize: 10, Endpoints: []string{server.URL}})
+	if err == nil || collector.IsRetryable(err) {
+		t.Fatalf("expected a fatal error, got %v", err)
+	}
+}
+
+func TestStartFailsOnTrustPolicyViolation(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := collector.NewArchiveWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WritePage([]collector.Bundle{validationTestBundle(0, "kyve1bad"), validationTestBundle(1, "kyve1good")}); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := collector.OpenArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	// Untrusted bundles are held back with the hold action, the load must not finish successfully
+	policy := &collector.TrustPolicy{DeniedUploaders: []string{"kyve1bad"}, Action: collector.PolicyHold}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	err = startTestLoader(t, collector.SourceConfig{PoolId: 1, ToBundleId: 100, BatchSize: 10, Archive: archive, TrustPolicy: policy})
+	var violation *collector.PolicyViolation
+	if !errors.As(err, &violation) || violation.BundleId != "0" {
+		t.Fatalf("expected a violation of bundle 0, got %v", err)
+	}
+}
@@ -1,10 +1,13 @@
 package loader
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"runtime/debug"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/KYVENetwork/KYVE-DLT/destinations"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
@@ -13,7 +16,7 @@ import (
 	"github.com/google/uuid"
 )
 
-func SetupLoader(configPath, connection string, setTo bool, from, to int64, force bool) (*Loader, error) {
+func SetupLoader(configPath, connection string, setTo bool, from, to int64, force bool, selection RangeSelection) (*Loader, error) {
 	if !setTo {
 		to = math.MaxInt64
 	}
@@ -26,6 +29,10 @@ func SetupLoader(configPath, connection string, setTo bool, from, to int64, forc
 	utils.GLOBAL_MAX_RAM_GB = uint64(config.Loader.MaxRamGB)
 	debug.SetMemoryLimit(int64(config.Loader.MaxRamGB * 1024 * 1024 * 1024))
 
+	if err := SetupStorage(config); err != nil {
+		return nil, err
+	}
+
 	source, destination, err := utils.GetConnectionDetails(config, connection)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read connection: %v", err)
@@ -34,13 +41,28 @@ func SetupLoader(configPath, connection string, setTo bool, from, to int64, forc
 	var dest destinations.Destination
 	switch destination.Type {
 	case "big_query":
+		var partitioning destinations.BigQueryPartitioningConfig
+		if destination.Partitioning != nil {
+			partitioning = getPartitioningConfig(*destination.Partitioning)
+		}
+
 		bigQueryDest := destinations.NewBigQuery(destinations.BigQueryConfig{
-			ProjectId:           destination.ProjectID,
-			DatasetId:           destination.DatasetID,
-			TableId:             destination.TableID,
-			BucketName:          destination.BucketName,
-			BigQueryWorkerCount: destination.WorkerCount,
-			BucketWorkerCount:   destination.BucketWorkerCount,
+			ProjectId:                 destination.ProjectID,
+			DatasetId:                 destination.DatasetID,
+			TableId:                   destination.TableID,
+			BucketName:                destination.BucketName,
+			CredentialsFile:           destination.CredentialsFile,
+			ImpersonateServiceAccount: destination.Impersonate,
+			BigQueryEndpoint:          destination.BigQueryEndpoint,
+			StorageEndpoint:           destination.StorageEndpoint,
+			WithoutAuthentication:     destination.WithoutAuth,
+			WriteMethod:               destination.WriteMethod,
+			BigQueryWorkerCount:       destination.WorkerCount,
+			BucketWorkerCount:         destination.BucketWorkerCount,
+			LoadJobMaxFiles:           destination.LoadJobMaxFiles,
+			LoadJobMaxBytes:           int64(destination.LoadJobMaxSizeMB) * 1024 * 1024,
+			LoadJobMaxWait:            time.Duration(destination.LoadJobMaxWait) * time.Second,
+			Partitioning:              partitioning,
 		})
 		dest = &bigQueryDest
 	case "postgres":
@@ -48,49 +70,192 @@ func SetupLoader(configPath, connection string, setTo bool, from, to int64, forc
 			ConnectionUrl:       destination.ConnectionURL,
 			TableName:           destination.TableName,
 			PostgresWorkerCount: destination.WorkerCount,
-			RowInsertLimit:      destination.RowInsertLimit,
 		})
 		dest = &postgresDest
 	default:
 		panic(fmt.Errorf("destination type not supported: %v", destination.Type))
 	}
 
+	sourceConfig, err := GetSourceConfig(source, from, to)
+	if err != nil {
+		return nil, err
+	}
+	// The archive of offline sources is closed by the loader, unless the setup fails
+	ok := false
+	defer func() {
+		if !ok && sourceConfig.Archive != nil {
+			sourceConfig.Archive.Close()
+		}
+	}()
+	sourceConfig.PartialSync = setTo
+	sourceConfig.Force = force
+
+	if selection.IsSet() {
+		if err := resolveRange(context.Background(), &sourceConfig, selection); err != nil {
+			return nil, fmt.Errorf("failed to resolve bundle range: %v", err)
+		}
+	}
+
+	schemaName, sourceSchema, err := GetSourceSchema(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var validation ValidationConfig
+	if source.Validation != nil {
+		validation = ValidationConfig{
+			Mode:           ValidationMode(source.Validation.Mode),
+			ContiguousKeys: source.Validation.ContiguousKeys,
+			Summary:        source.Validation.Summary,
+		}
+	}
+	if err := validation.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid validation config of source %s: %v", source.Name, err)
+	}
+
+	loaderConfig := Config{
+		ChannelSize:    config.Loader.ChannelSize,
+		CsvWorkerCount: config.Loader.CSVWorkerCount,
+		SourceSchema:   sourceSchema,
+		MemoryBudget:   int64(config.Loader.MaxRamGB) * 1024 * 1024 * 1024,
+		Validation:     validation,
+	}
+
+	statusProperties := StatusProperties{
+		syncId:                  uuid.New().String(),
+		schemaType:              schemaName,
+		destinationType:         destination.Type,
+		uncompressedBytesSynced: new(atomic.Int64),
+		compressedBytesSynced:   new(atomic.Int64),
+		bundlesSynced:           new(atomic.Int64),
+	}
+
+	ok = true
+	return NewLoader(loaderConfig, sourceConfig, dest, connection, statusProperties), nil
+}
+
+func getPartitioningConfig(partitioning utils.Partitioning) destinations.BigQueryPartitioningConfig {
+	config := destinations.BigQueryPartitioningConfig{
+		Field:            partitioning.Field,
+		Type:             partitioning.Type,
+		RangeStart:       partitioning.RangeStart,
+		RangeEnd:         partitioning.RangeEnd,
+		RangeInterval:    partitioning.RangeInterval,
+		ClusteringFields: partitioning.Clustering,
+	}
+	if len(partitioning.Tables) > 0 {
+		config.Tables = make(map[string]destinations.BigQueryPartitioningConfig, len(partitioning.Tables))
+		for name, table := range partitioning.Tables {
+			config.Tables[name] = getPartitioningConfig(table)
+		}
+	}
+	return config
+}
+
+// GetSourceConfig returns the collector config of a source for the given bundle range.
+func GetSourceConfig(source utils.Source, from, to int64) (collector.SourceConfig, error) {
 	sourceConfig := collector.SourceConfig{
 		PoolId:       int64(source.PoolID),
 		FromBundleId: from,
 		ToBundleId:   to,
 		BatchSize:    int64(source.BatchSize),
-		Endpoint:     source.Endpoint,
-		PartialSync:  setTo,
-		Force:        force,
+		Endpoints:    source.GetEndpoints(),
+		Client: collector.ClientConfig{
+			Timeout:    time.Duration(source.Timeout) * time.Second,
+			MaxRetries: source.MaxRetries,
+		},
+	}
+
+	if source.TrustPolicy != nil {
+		policy := &collector.TrustPolicy{
+			MinVotePowerRatio:  source.TrustPolicy.MinVotePowerRatio,
+			AllowedUploaders:   source.TrustPolicy.AllowedUploaders,
+			DeniedUploaders:    source.TrustPolicy.DeniedUploaders,
+			MinFinalizationAge: time.Duration(source.TrustPolicy.MinFinalizationAge) * time.Second,
+			Action:             collector.PolicyAction(source.TrustPolicy.OnViolation),
+			QuarantineFile:     source.TrustPolicy.QuarantineFile,
+		}
+		if err := policy.Validate(); err != nil {
+			return collector.SourceConfig{}, fmt.Errorf("invalid trust policy of source %s: %v", source.Name, err)
+		}
+		sourceConfig.TrustPolicy = policy
+	}
+
+	// Offline sources read the bundles from a local archive instead of the KYVE API
+	if source.Path != "" {
+		archive, err := collector.OpenArchive(source.Path)
+		if err != nil {
+			return collector.SourceConfig{}, err
+		}
+		sourceConfig.Archive = archive
+		return sourceConfig, nil
+	}
+
+	if len(sourceConfig.Endpoints) == 0 {
+		return collector.SourceConfig{}, fmt.Errorf("no endpoint specified for source %s", source.Name)
+	}
+	return sourceConfig, nil
+}
+
+// GetSourceSchema returns the configured schema of a source, including provenance columns.
+func GetSourceSchema(source utils.Source) (string, schema.DataSource, error) {
+	schemaName := source.Schema
+	if schemaName == schema.Auto {
+		return "", nil, fmt.Errorf("schema of source %s is %s, which is only resolved by dlt sources add, set one of %s",
+			source.Name, schema.Auto, strings.Join(schema.Names, ", "))
 	}
 
 	var sourceSchema schema.DataSource
-	switch source.Schema {
+	switch schemaName {
 	case "base":
 		sourceSchema = schema.Base{}
 	case "height":
 		sourceSchema = schema.Height{}
 	case "tendermint_preprocessed":
 		sourceSchema = schema.TendermintPreProcessed{}
+	case "evm":
+		sourceSchema = schema.Evm{}
+	case "cosmos_txs":
+		registry, err := schema.LoadProtoRegistry(source.ProtoRegistries)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to load proto registries of source %s: %v", source.Name, err)
+		}
+		sourceSchema = schema.CosmosTxs{Registry: registry}
+	case "tendermint_events":
+		if source.Base64AttributesBelowHeight < 0 {
+			return "", nil, fmt.Errorf("base64_attributes_below_height of source %s must not be negative", source.Name)
+		}
+		if source.Base64AttributesBelowHeight != 0 && !source.Base64Attributes {
+			return "", nil, fmt.Errorf("base64_attributes_below_height of source %s requires base64_attributes", source.Name)
+		}
+		sourceSchema = schema.TendermintEvents{
+			Base64Attributes:            source.Base64Attributes,
+			Base64AttributesBelowHeight: source.Base64AttributesBelowHeight,
+		}
 	default:
-		panic(fmt.Errorf("source schema not supported: %v", source.Schema))
+		return "", nil, fmt.Errorf("source schema not supported: %v", schemaName)
+	}
+	if source.Provenance {
+		sourceSchema = schema.WithProvenance(sourceSchema)
 	}
+	return schemaName, sourceSchema, nil
+}
 
-	loaderConfig := Config{
-		ChannelSize:    config.Loader.ChannelSize,
-		CsvWorkerCount: config.Loader.CSVWorkerCount,
-		SourceSchema:   sourceSchema,
+// SetupStorage configures the storage providers and the bundle cache used for all bundle downloads.
+func SetupStorage(config *utils.Config) error {
+	if err := schema.SetStorageProviders(config.StorageProviders); err != nil {
+		return fmt.Errorf("invalid storage providers: %v", err)
 	}
 
-	statusProperties := StatusProperties{
-		syncId:                  uuid.New().String(),
-		schemaType:              source.Schema,
-		destinationType:         destination.Type,
-		uncompressedBytesSynced: new(atomic.Int64),
-		compressedBytesSynced:   new(atomic.Int64),
-		bundlesSynced:           new(atomic.Int64),
+	if !config.Cache.Enabled {
+		schema.SetBundleCache(nil)
+		return nil
 	}
 
-	return NewLoader(loaderConfig, sourceConfig, dest, connection, statusProperties), nil
+	cache, err := schema.NewBundleCache(utils.GetCacheDir(config), int64(config.Cache.MaxSizeGB)*1024*1024*1024)
+	if err != nil {
+		return err
+	}
+	schema.SetBundleCache(cache)
+	return nil
 }
@@ -2,6 +2,7 @@ package loader
 
 import (
 	"fmt"
+	"time"
 )
 
 type Status struct {
@@ -9,7 +10,7 @@ type Status struct {
 	ToBundleId   int64
 	FromKey      string
 	ToKey        string
-	ExtractedAt  string
+	ExtractedAt  time.Time
 }
 
 func (s Status) String() string {
@@ -1,9 +1,12 @@
 package loader
 
 import (
+	"context"
 	"github.com/KYVENetwork/KYVE-DLT/destinations"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
 	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -11,6 +14,8 @@ import (
 
 type BundlesBusItem struct {
 	bundles []collector.Bundle
+	// Position of the item in the order the bundles were fetched
+	sequence int64
 
 	status Status
 }
@@ -29,7 +34,20 @@ type Loader struct {
 
 	latestBundleId *int64
 
+	memory *utils.MemoryGovernor
+	// Size of the row chunks passed to the destination
+	chunkSize int64
+	// Upper limit of the memory reserved for a bundle before it is downloaded
+	bundleMemoryLimit int64
+	validator         *Validator
+
 	statusProperties StatusProperties
+
+	// aborted is canceled if the load fails, err holds the reason
+	aborted  context.Context
+	abort    context.CancelFunc
+	errMutex sync.Mutex
+	err      error
 }
 
 type StatusProperties struct {
@@ -46,14 +64,41 @@ type Config struct {
 	ChannelSize    int
 	CsvWorkerCount int
 	SourceSchema   schema.DataSource
+	// Bytes of converted bundles held in memory at the same time, 0 disables the limit
+	MemoryBudget int64
+	Validation   ValidationConfig
 }
 
 func NewLoader(loaderConfig Config, sourceConfig collector.SourceConfig, destination destinations.Destination, connectionName string, properties StatusProperties) *Loader {
+	validator := NewValidator(loaderConfig.Validation, connectionName)
+	if sourceConfig.TrustPolicy != nil {
+		sourceConfig.TrustPolicy.OnViolation = func(bundle collector.Bundle, violation *collector.PolicyViolation) {
+			utils.PrometheusBundlesUntrusted.WithLabelValues(connectionName).Inc()
+			// Quarantined bundles leave a gap which is not a continuity violation
+			if sourceConfig.TrustPolicy.Action == collector.PolicyQuarantine {
+				validator.Skip(bundle)
+			}
+		}
+	}
+
 	return &Loader{
-		config:           loaderConfig,
-		sourceConfig:     sourceConfig,
-		destination:      destination,
-		ConnectionName:   connectionName,
-		statusProperties: properties,
+		config:            loaderConfig,
+		sourceConfig:      sourceConfig,
+		destination:       destination,
+		ConnectionName:    connectionName,
+		statusProperties:  properties,
+		memory:            utils.NewMemoryGovernor(connectionName, loaderConfig.MemoryBudget),
+		chunkSize:         destinations.RowChunkSize(loaderConfig.MemoryBudget, loaderConfig.CsvWorkerCount),
+		bundleMemoryLimit: bundleMemoryLimit(loaderConfig.MemoryBudget, loaderConfig.CsvWorkerCount),
+		validator:         validator,
+	}
+}
+
+// bundleMemoryLimit returns the upper limit of the reservation of a bundle. Every worker can reserve
+// half of its share of the budget for the bundle it converts, the chunks of rows take the other half.
+func bundleMemoryLimit(memoryBudget int64, workers int) int64 {
+	if memoryBudget <= 0 {
+		return math.MaxInt64
 	}
+	return max(memoryBudget/int64(2*max(workers, 1)), 1)
 }
@@ -0,0 +1,202 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/schema"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+type ValidationMode string
+
+const (
+	ValidationOff ValidationMode = "off"
+	// ValidationWarn logs and counts violations, but loads the bundles anyway
+	ValidationWarn ValidationMode = "warn"
+	// ValidationStrict stops the load at the first violation
+	ValidationStrict ValidationMode = "strict"
+)
+
+// Summary formats which can be verified
+const (
+	// The bundle_summary is the key of the last item
+	SummaryToKey = "to_key"
+)
+
+type ValidationConfig struct {
+	Mode ValidationMode
+	// Integer keys have to increase by one, across bundles as well
+	ContiguousKeys bool
+	// Format of the bundle_summary, empty if it is not deterministic
+	Summary string
+}
+
+func (c *ValidationConfig) Validate() error {
+	switch c.Mode {
+	case "":
+		c.Mode = ValidationWarn
+	case ValidationOff, ValidationWarn, ValidationStrict:
+	default:
+		return fmt.Errorf("unknown validation mode %q, expected off, warn or strict", c.Mode)
+	}
+	switch c.Summary {
+	case "", SummaryToKey:
+	default:
+		return fmt.Errorf("unknown summary format %q", c.Summary)
+	}
+	return nil
+}
+
+// ValidationError is a violated invariant of a bundle.
+type ValidationError struct {
+	BundleId string
+	Check    string
+	Expected string
+	Actual   string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("bundle %s failed %s check: expected %s, got %s", e.BundleId, e.Check, e.Expected, e.Actual)
+}
+
+// Validator checks that the decoded bundles match their metadata and follow each other.
+type Validator struct {
+	config     ValidationConfig
+	connection string
+
+	mu       sync.Mutex
+	previous *collector.Bundle
+	// Bundles dropped by the trust policy by their from_index
+	skipped map[string]collector.Bundle
+}
+
+func NewValidator(config ValidationConfig, connection string) *Validator {
+	return &Validator{config: config, connection: connection, skipped: make(map[string]collector.Bundle)}
+}
+
+// Skip records a bundle which is not loaded on purpose, e.g. because it was quarantined.
+// The bundles after it are checked against it instead of the last loaded bundle.
+func (v *Validator) Skip(bundle collector.Bundle) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.skipped[bundle.FromIndex] = bundle
+}
+
+// CheckContinuity verifies that the bundle directly follows the previous one, bundles
+// have to be passed in order.
+func (v *Validator) CheckContinuity(bundle collector.Bundle) error {
+	if v.config.Mode == ValidationOff {
+		return nil
+	}
+
+	v.mu.Lock()
+	previous := v.previous
+	for previous != nil && previous.ToIndex != bundle.FromIndex {
+		skipped, ok := v.skipped[previous.ToIndex]
+		if !ok {
+			break
+		}
+		delete(v.skipped, previous.ToIndex)
+		previous = &skipped
+	}
+	v.previous = &bundle
+	v.mu.Unlock()
+
+	if previous == nil {
+		return nil
+	}
+
+	var errs []error
+	if bundle.FromIndex != previous.ToIndex {
+		errs = append(errs, &ValidationError{BundleId: bundle.Id, Check: "index_continuity", Expected: previous.ToIndex, Actual: bundle.FromIndex})
+	}
+	if v.config.ContiguousKeys {
+		if expected, ok := nextKey(previous.ToKey); !ok || bundle.FromKey != expected {
+			errs = append(errs, &ValidationError{BundleId: bundle.Id, Check: "key_continuity", Expected: expected, Actual: bundle.FromKey})
+		}
+	}
+	return v.report(errors.Join(errs...))
+}
+
+// CheckBundle verifies the decoded items against the metadata of the bundle.
+func (v *Validator) CheckBundle(bundle collector.Bundle, items schema.ItemStats) error {
+	if v.config.Mode == ValidationOff {
+		return nil
+	}
+
+	var errs []error
+	fail := func(check, expected, actual string) {
+		errs = append(errs, &ValidationError{BundleId: bundle.Id, Check: check, Expected: expected, Actual: actual})
+	}
+
+	fromIndex, errFrom := strconv.ParseInt(bundle.FromIndex, 10, 64)
+	toIndex, errTo := strconv.ParseInt(bundle.ToIndex, 10, 64)
+	if errFrom != nil || errTo != nil {
+		fail("item_count", "numeric from_index and to_index", fmt.Sprintf("%q and %q", bundle.FromIndex, bundle.ToIndex))
+	} else if toIndex-fromIndex != items.Count {
+		fail("item_count", strconv.FormatInt(toIndex-fromIndex, 10), strconv.FormatInt(items.Count, 10))
+	}
+
+	if items.FirstKey != bundle.FromKey {
+		fail("from_key", bundle.FromKey, items.FirstKey)
+	}
+	if items.LastKey != bundle.ToKey {
+		fail("to_key", bundle.ToKey, items.LastKey)
+	}
+	if v.config.ContiguousKeys && items.GapIndex >= 0 {
+		fail("key_continuity", "contiguous keys", fmt.Sprintf("gap at item %d", items.GapIndex))
+	}
+
+	if v.config.Summary == SummaryToKey && bundle.BundleSummary != items.LastKey {
+		fail("bundle_summary", bundle.BundleSummary, items.LastKey)
+	}
+
+	return v.report(errors.Join(errs...))
+}
+
+// report counts the violations and returns them in strict mode.
+func (v *Validator) report(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	for _, e := range unwrapAll(err) {
+		var validationError *ValidationError
+		if errors.As(e, &validationError) {
+			utils.PrometheusBundleValidationFailures.WithLabelValues(v.connection, validationError.Check).Inc()
+			logger.Warn().
+				Str("connection", v.connection).
+				Str("bundle_id", validationError.BundleId).
+				Str("check", validationError.Check).
+				Str("expected", validationError.Expected).
+				Str("actual", validationError.Actual).
+				Msg("bundle validation failed")
+		}
+	}
+
+	if v.config.Mode == ValidationStrict {
+		return err
+	}
+	return nil
+}
+
+func unwrapAll(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+func nextKey(key string) (string, bool) {
+	n, ok := new(big.Int).SetString(key, 10)
+	if !ok {
+		return "", false
+	}
+	return n.Add(n, big.NewInt(1)).String(), true
+}
@@ -0,0 +1,94 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+)
+
+func validationTestBundle(id int64, uploader string) collector.Bundle {
+	b := collector.Bundle{
+		PoolId:    "1",
+		Id:        strconv.FormatInt(id, 10),
+		Uploader:  uploader,
+		FromIndex: strconv.FormatInt(10*id, 10),
+		ToIndex:   strconv.FormatInt(10*id+10, 10),
+		FromKey:   strconv.FormatInt(10*id+1, 10),
+		ToKey:     strconv.FormatInt(10*id+10, 10),
+	}
+	b.StakeSecurity.ValidVotePower = "1"
+	b.StakeSecurity.TotalVotePower = "1"
+	b.FinalizedAt.Timestamp = time.Now().Add(-time.Hour)
+	return b
+}
+
+// TestQuarantineWithStrictValidation loads the bundles like the collector of the loader,
+// a quarantined bundle must not be reported as gap of the bundles after it.
+func TestQuarantineWithStrictValidation(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := collector.NewArchiveWriter(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundles := []collector.Bundle{
+		validationTestBundle(0, "kyve1good"),
+		validationTestBundle(1, "kyve1bad"),
+		validationTestBundle(2, "kyve1bad"),
+		validationTestBundle(3, "kyve1good"),
+	}
+	if err := writer.WritePage(bundles); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := collector.OpenArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	policy := &collector.TrustPolicy{DeniedUploaders: []string{"kyve1bad"}, Action: collector.PolicyQuarantine}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewLoader(
+		Config{Validation: ValidationConfig{Mode: ValidationStrict, ContiguousKeys: true}},
+		collector.SourceConfig{PoolId: 1, ToBundleId: 100, BatchSize: 10, Archive: archive, TrustPolicy: policy},
+		nil, "test", StatusProperties{},
+	)
+
+	source, err := collector.NewSource(loader.sourceConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := source.Bundles(0).Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 {
+		t.Fatalf("expected bundles 0 and 3, got %d bundles", len(loaded))
+	}
+	for _, bundle := range loaded {
+		if err := loader.validator.CheckContinuity(bundle); err != nil {
+			t.Fatalf("unexpected violation of bundle %s: %v", bundle.Id, err)
+		}
+	}
+}
+
+func TestCheckContinuityDetectsGap(t *testing.T) {
+	validator := NewValidator(ValidationConfig{Mode: ValidationStrict}, "test")
+
+	if err := validator.CheckContinuity(validationTestBundle(0, "")); err != nil {
+		t.Fatal(err)
+	}
+	// Skipped bundles only close the gap they cover
+	validator.Skip(validationTestBundle(1, ""))
+
+	err := validator.CheckContinuity(validationTestBundle(3, ""))
+	var validationError *ValidationError
+	if !errors.As(err, &validationError) || validationError.Check != "index_continuity" {
+		t.Fatalf("expected index_continuity violation, got %v", err)
+	}
+}
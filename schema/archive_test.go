@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// testArchiveDir is the archive fixture of the collector, it contains three bundles
+// of pool 1 with the heights 1 to 9.
+const testArchiveDir = "../loader/collector/testdata/archive"
+
+func archiveBundles(t *testing.T, archive *collector.Archive) []collector.Bundle {
+	t.Helper()
+
+	source, err := collector.NewSource(collector.SourceConfig{PoolId: 1, ToBundleId: 100, BatchSize: 10, Archive: archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundles, err := source.Bundles(0).Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bundles
+}
+
+// collectRows returns a sink which appends all rows to the slice.
+func collectRows(rows *[]DataRow) RowSink {
+	return RowSinkFunc(func(row DataRow) error {
+		*rows = append(*rows, row)
+		return nil
+	})
+}
+
+func TestConvertArchive(t *testing.T) {
+	archive, err := collector.OpenArchive(testArchiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	extractedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var heights []int64
+	for _, bundle := range archiveBundles(t, archive) {
+		var rows []DataRow
+		result, err := Height{}.DownloadAndConvertBundle(bundle, ExtraData{ExtractedAt: extractedAt, Archive: archive}, collectRows(&rows))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Items.Count != 3 || result.Items.FirstKey != bundle.FromKey || result.Items.LastKey != bundle.ToKey {
+			t.Fatalf("unexpected items of bundle %s: %+v", bundle.Id, result.Items)
+		}
+
+		info, err := os.Stat(filepath.Join(testArchiveDir, collector.ArchiveStorageDir, bundle.StorageId))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.CompressedSize != info.Size() {
+			t.Fatalf("compressed size %d, want %d", result.CompressedSize, info.Size())
+		}
+
+		for _, row := range rows {
+			heightRow := row.(HeightRow)
+			if heightRow._dlt_extracted_at != extractedAt {
+				t.Fatalf("unexpected extracted_at %s", heightRow._dlt_extracted_at)
+			}
+			heights = append(heights, heightRow.height)
+		}
+	}
+
+	for i, height := range heights {
+		if height != int64(i+1) {
+			t.Fatalf("got heights %v, want 1 to 9", heights)
+		}
+	}
+	if len(heights) != 9 {
+		t.Fatalf("got heights %v, want 1 to 9", heights)
+	}
+}
+
+func TestConvertArchiveCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	for _, subDir := range []string{collector.ArchiveBundlesDir, collector.ArchiveStorageDir} {
+		entries, err := os.ReadDir(filepath.Join(testArchiveDir, subDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, subDir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			data, err := os.ReadFile(filepath.Join(testArchiveDir, subDir, entry.Name()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Flip a byte of the first bundle after the gzip header
+			if entry.Name() == "bundle-0" {
+				data[len(data)/2] ^= 0xff
+			}
+			if err := os.WriteFile(filepath.Join(dir, subDir, entry.Name()), data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	archive, err := collector.OpenArchive(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	_, err = Height{}.DownloadAndConvertBundle(archiveBundles(t, archive)[0], ExtraData{Archive: archive}, collectRows(new([]DataRow)))
+	var permanentErr *utils.PermanentError
+	if !errors.As(err, &permanentErr) {
+		t.Fatalf("expected permanent checksum error, got %v", err)
+	}
+}
+
+func TestConvertArchiveOnItem(t *testing.T) {
+	archive, err := collector.OpenArchive(testArchiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	// Items and rows are read in the same pass, the key range applies to both
+	var keys []string
+	var rows []DataRow
+	extra := ExtraData{
+		Archive:  archive,
+		KeyRange: collector.KeyRange{FromKey: "2", ToKey: "2"},
+		OnItem: func(item BaseItem) error {
+			keys = append(keys, item.Key)
+			return nil
+		},
+	}
+	result, err := Height{}.DownloadAndConvertBundle(archiveBundles(t, archive)[0], extra, collectRows(&rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Items.Count != 3 || len(keys) != 1 || keys[0] != "2" || len(rows) != 1 {
+		t.Fatalf("unexpected items %v and %d rows of %d items", keys, len(rows), result.Items.Count)
+	}
+}
@@ -3,11 +3,10 @@ package schema
 import (
 	"cloud.google.com/go/bigquery"
 	"encoding/json"
-	"fmt"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
-	"github.com/KYVENetwork/KYVE-DLT/utils"
-	"github.com/google/uuid"
+	"io"
 	"strconv"
+	"time"
 )
 
 type BaseItem struct {
@@ -15,105 +14,83 @@ type BaseItem struct {
 	Value json.RawMessage `json:"value"`
 }
 
+func (i BaseItem) itemKey() string {
+	return i.Key
+}
+
 type BaseRow struct {
 	_dlt_raw_id       string
-	_dlt_extracted_at string
+	_dlt_extracted_at time.Time
 	key               string
-	value             string
+	value             json.RawMessage
 	bundle_id         int64
 }
 
-func (t BaseRow) ConvertToCSVLine() []string {
-	return []string{
-		uuid.New().String(),
+func (t BaseRow) TableName() string {
+	return ""
+}
+
+func (t BaseRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.key),
 		t._dlt_extracted_at,
 		t.key,
 		t.value,
-		strconv.FormatInt(t.bundle_id, 10),
+		t.bundle_id,
 	}
 }
 
 type Base struct{}
 
-func (t Base) GetBigQuerySchema() bigquery.Schema {
-	return bigquery.Schema{
-		{Name: "_dlt_raw_id", Type: bigquery.StringFieldType},
-		{Name: "_dlt_extracted_at", Type: bigquery.TimestampFieldType},
-		{Name: "key", Type: bigquery.StringFieldType},
-		{Name: "value", Type: bigquery.JSONFieldType},
-		{Name: "bundle_id", Type: bigquery.IntegerFieldType},
-	}
-}
-
-func (t Base) GetBigQueryTimePartitioning() *bigquery.TimePartitioning {
-	return &bigquery.TimePartitioning{
-		Field: "_dlt_extracted_at",
-		Type:  bigquery.DayPartitioningType,
-	}
-}
-
-func (t Base) GetBigQueryClustering() *bigquery.Clustering {
-	return &bigquery.Clustering{Fields: []string{"_dlt_extracted_at"}}
+func (t Base) Tables() []Table {
+	return []Table{{
+		Columns: columns(
+			Column{Name: "key", Type: StringColumn, Required: true},
+			Column{Name: "value", Type: JSONColumn},
+		),
+		PrimaryKey: []string{"key"},
+		TimePartitioning: &bigquery.TimePartitioning{
+			Field: "_dlt_extracted_at",
+			Type:  bigquery.DayPartitioningType,
+		},
+		Clustering: &bigquery.Clustering{Fields: []string{"_dlt_extracted_at"}},
+	}}
 }
 
-func (t Base) GetCSVSchema() []string {
-	return []string{
-		"_dlt_raw_id",
-		"_dlt_extracted_at",
-		"key",
-		"value",
-		"bundle_id",
-	}
-}
-
-func (t Base) GetPostgresCreateTableCommand(name string) string {
-	return fmt.Sprintf(`
-CREATE TABLE IF NOT EXISTS %s (
-    _dlt_raw_id varchar NOT NULL,
-    _dlt_extracted_at timestamp NOT NULL,
-    "key" varchar NOT NULL, 
-    "value" varchar, 
-    "bundle_id" integer NOT NULL, 
-    PRIMARY KEY (key)
-    )
-    `, name)
-}
-
-func (t Base) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData) (Result, error) {
-	downloadResult, err := downloadBundle(bundle, extra)
-	if err != nil {
-		return Result{}, err
-	}
-
-	var items []BaseItem
-	err = json.Unmarshal(downloadResult.Data.Bytes(), &items)
-	if err != nil {
-		return Result{}, err
-	}
-
+func (t Base) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
 	bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
 
-	columns := make([]DataRow, 0)
-	for _, kyveItem := range items {
-		utils.AwaitEnoughMemory(extra.Name)
-
-		jsonValue, err := json.Marshal(kyveItem.Value)
-		if err != nil {
-			return Result{}, err
-		}
-		columns = append(columns, BaseRow{
-			_dlt_raw_id:       "",
-			_dlt_extracted_at: extra.ExtractedAt,
-			value:             string(jsonValue),
-			key:               kyveItem.Key,
-			bundle_id:         int64(bundleId),
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem BaseItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			jsonValue, err := json.Marshal(kyveItem.Value)
+			if err != nil {
+				return err
+			}
+			if err := sink.Write(BaseRow{
+				_dlt_raw_id:       "",
+				_dlt_extracted_at: extra.ExtractedAt,
+				value:             jsonValue,
+				key:               kyveItem.Key,
+				bundle_id:         int64(bundleId),
+			}); err != nil {
+				return err
+			}
+			return nil
 		})
-
+		return err
+	})
+	if err != nil {
+		return Result{}, err
 	}
 
 	return Result{
-		Data:             columns,
 		CompressedSize:   downloadResult.CompressedSize,
 		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
 	}, nil
 }
@@ -0,0 +1,323 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+type bundleOrigin int
+
+const (
+	originStorageProvider bundleOrigin = iota
+	originArchive
+	originCache
+)
+
+// bundleStream reads the compressed data of a bundle and computes its checksum on the fly.
+// Verify has to be called after the data was consumed and before any of it is used.
+type bundleStream struct {
+	bundle collector.Bundle
+	origin bundleOrigin
+	body   io.ReadCloser
+	// Gateway which serves the data if it is downloaded from a storage provider
+	gateway Gateway
+
+	hash hash.Hash
+	size int64
+	// Size of the data before it is read, -1 if it is unknown
+	length int64
+
+	// Downloaded data is written to the cache while it is read
+	cache *cacheWriter
+}
+
+// openBundle returns the compressed data of the bundle. If an archive is given, the data is
+// read from it. Otherwise, the bundle cache is consulted first and downloaded bundles are
+// added to the cache once they were verified.
+func openBundle(bundle collector.Bundle, archive *collector.Archive) (*bundleStream, error) {
+	stream := &bundleStream{bundle: bundle, hash: sha256.New()}
+
+	if archive != nil {
+		body, err := archive.OpenBlob(bundle.StorageId)
+		if err != nil {
+			return nil, utils.Permanent(fmt.Errorf("failed to read bundle %s from archive: %w", bundle.Id, err))
+		}
+		stream.origin, stream.body, stream.length = originArchive, body, bodyLength(body)
+		return stream, nil
+	}
+
+	if bundleCache != nil {
+		if body, ok := bundleCache.Open(bundle.DataHash); ok {
+			stream.origin, stream.body, stream.length = originCache, body, bodyLength(body)
+			return stream, nil
+		}
+	}
+
+	provider, err := GetStorageProvider(bundle.StorageProviderId)
+	if err != nil {
+		return nil, utils.Permanent(err)
+	}
+
+	body, gateway, err := openFromGateways(provider, bundle.StorageId)
+	if err != nil {
+		return nil, err
+	}
+	stream.origin, stream.body, stream.gateway, stream.length = originStorageProvider, body, gateway, bodyLength(body)
+
+	if bundleCache != nil {
+		writer, err := bundleCache.Create(bundle.DataHash)
+		if err != nil {
+			logger.Warn().Str("bundle_id", bundle.Id).Str("err", err.Error()).Msg("failed to add bundle to cache")
+		} else {
+			stream.cache = writer
+		}
+	}
+
+	return stream, nil
+}
+
+// bodyLength returns the size of the body if it is known before it is read, otherwise -1.
+func bodyLength(body io.ReadCloser) int64 {
+	switch b := body.(type) {
+	case interface{ Size() int64 }:
+		return b.Size()
+	case *os.File:
+		if info, err := b.Stat(); err == nil {
+			return info.Size()
+		}
+	}
+	return -1
+}
+
+func (s *bundleStream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if n > 0 {
+		s.hash.Write(p[:n])
+		s.size += int64(n)
+
+		if s.cache != nil {
+			if _, err := s.cache.Write(p[:n]); err != nil {
+				logger.Warn().Str("bundle_id", s.bundle.Id).Str("err", err.Error()).Msg("failed to add bundle to cache")
+				s.cache.Abort()
+				s.cache = nil
+			}
+		}
+	}
+	return n, err
+}
+
+// Verify reads the remaining data and compares the checksum with the data_hash of the bundle.
+func (s *bundleStream) Verify() error {
+	if _, err := io.Copy(io.Discard, s); err != nil {
+		return err
+	}
+
+	if hex.EncodeToString(s.hash.Sum(nil)) != s.bundle.DataHash {
+		switch s.origin {
+		case originArchive:
+			return utils.Permanent(fmt.Errorf("checksum of bundle %s in archive does not match", s.bundle.Id))
+		case originCache:
+			logger.Warn().Str("data_hash", s.bundle.DataHash).Msg("removing corrupted bundle from cache")
+			bundleCache.Remove(s.bundle.DataHash)
+			return errors.New("checksum of cached bundle does not match")
+		default:
+			logger.Warn().Str("bundle_id", s.bundle.Id).Str("gateway", s.gateway.Url).Msg("gateway served corrupted bundle, skipping it on retry")
+			markGatewayCorrupt(s.bundle.StorageId, s.gateway)
+			return fmt.Errorf("checksum of bundle from %s does not match", s.gateway.Url)
+		}
+	}
+	if s.origin == originStorageProvider {
+		forgetCorruptGateways(s.bundle.StorageId)
+	}
+
+	if s.cache != nil {
+		if err := s.cache.Commit(); err != nil {
+			logger.Warn().Str("bundle_id", s.bundle.Id).Str("err", err.Error()).Msg("failed to add bundle to cache")
+		}
+		s.cache = nil
+	}
+	return nil
+}
+
+func (s *bundleStream) Close() error {
+	if s.cache != nil {
+		s.cache.Abort()
+		s.cache = nil
+	}
+	return s.body.Close()
+}
+
+// FetchBundle returns the verified compressed data of the bundle, see openBundle.
+func FetchBundle(bundle collector.Bundle, archive *collector.Archive) ([]byte, error) {
+	stream, err := openBundle(bundle, archive)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Verify(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// countingReader counts the decompressed data and grows the reservation of the bundle with it.
+type countingReader struct {
+	reader      io.Reader
+	count       int64
+	reservation *utils.MemoryReservation
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	if r.reservation != nil {
+		r.reservation.Grow(r.count)
+	}
+	return n, err
+}
+
+// downloadBundle passes the decompressed data of the bundle to convert while it is downloaded,
+// so that the bundle is never held in memory as a whole. The result of convert must only be
+// used if no error is returned, because the checksum can only be verified at the end.
+func downloadBundle(bundle collector.Bundle, extra ExtraData, convert func(data io.Reader) error) (DownloadResult, error) {
+	decompressor, err := getDecompressor(bundle.CompressionId)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+
+	stream, err := openBundle(bundle, extra.Archive)
+	if err != nil {
+		return DownloadResult{}, err
+	}
+	defer stream.Close()
+
+	var reservation *utils.MemoryReservation
+	if extra.Reserve != nil {
+		reservation, err = extra.Reserve(stream.length)
+		if err != nil {
+			return DownloadResult{}, err
+		}
+		defer reservation.Release()
+	}
+
+	// Corrupted data usually fails to decompress or to decode, in that
+	// case the checksum error is reported as it is more helpful.
+	reader, err := decompressor(stream)
+	if err != nil {
+		if verifyErr := stream.Verify(); verifyErr != nil {
+			return DownloadResult{}, verifyErr
+		}
+		return DownloadResult{}, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	defer reader.Close()
+
+	data := &countingReader{reader: reader, reservation: reservation}
+	if err := convert(data); err != nil {
+		if verifyErr := stream.Verify(); verifyErr != nil {
+			return DownloadResult{}, verifyErr
+		}
+		return DownloadResult{}, err
+	}
+
+	// Read the remaining data, so that the decompressor checks its own checksum
+	if _, err := io.Copy(io.Discard, data); err != nil {
+		if verifyErr := stream.Verify(); verifyErr != nil {
+			return DownloadResult{}, verifyErr
+		}
+		return DownloadResult{}, fmt.Errorf("failed to decompress bundle: %w", err)
+	}
+	if err := stream.Verify(); err != nil {
+		return DownloadResult{}, err
+	}
+
+	return DownloadResult{
+		CompressedSize:   stream.size,
+		UncompressedSize: data.count,
+	}, nil
+}
+
+// keyedItem is implemented by the items of all schemas.
+type keyedItem interface {
+	itemKey() string
+}
+
+// decodeItems decodes the JSON array of a bundle item by item, so that only a
+// single item is held in memory at the same time. The raw items are passed to
+// extra.OnItem as well if it is set.
+func decodeItems[T keyedItem](data io.Reader, extra ExtraData, fn func(item T) error) (ItemStats, error) {
+	decoder := json.NewDecoder(data)
+	stats := ItemStats{GapIndex: -1}
+
+	if err := expectDelim(decoder, '['); err != nil {
+		return stats, err
+	}
+	var previous int64
+	for decoder.More() {
+		var item T
+		var raw BaseItem
+		if extra.OnItem == nil {
+			if err := decoder.Decode(&item); err != nil {
+				return stats, fmt.Errorf("failed to decode bundle item: %w", err)
+			}
+		} else {
+			var data json.RawMessage
+			if err := decoder.Decode(&data); err != nil {
+				return stats, fmt.Errorf("failed to decode bundle item: %w", err)
+			}
+			if err := json.Unmarshal(data, &item); err != nil {
+				return stats, fmt.Errorf("failed to decode bundle item: %w", err)
+			}
+			if err := json.Unmarshal(data, &raw); err != nil {
+				return stats, fmt.Errorf("failed to decode bundle item: %w", err)
+			}
+		}
+
+		key := item.itemKey()
+		if stats.Count == 0 {
+			stats.FirstKey = key
+		}
+		current, err := strconv.ParseInt(key, 10, 64)
+		if stats.GapIndex < 0 && stats.Count > 0 && (err != nil || current != previous+1) {
+			stats.GapIndex = stats.Count
+		}
+		previous = current
+		stats.LastKey = key
+		stats.Count++
+
+		if extra.OnItem != nil && extra.KeyRange.Contains(key) {
+			if err := extra.OnItem(raw); err != nil {
+				return stats, err
+			}
+		}
+		if err := fn(item); err != nil {
+			return stats, err
+		}
+	}
+	return stats, expectDelim(decoder, ']')
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return fmt.Errorf("failed to decode bundle: %w", err)
+	}
+	if token != delim {
+		return fmt.Errorf("failed to decode bundle: expected %q, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package schema
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// testBundle returns a gzip compressed bundle with the given JSON items and its metadata.
+func testBundle(t *testing.T, items string) (collector.Bundle, []byte) {
+	t.Helper()
+
+	var data bytes.Buffer
+	writer := gzip.NewWriter(&data)
+	if _, err := writer.Write([]byte(items)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	hash := sha256.Sum256(data.Bytes())
+	return collector.Bundle{
+		PoolId:            "1",
+		Id:                "7",
+		StorageId:         "storage-id",
+		DataHash:          hex.EncodeToString(hash[:]),
+		StorageProviderId: "99",
+		CompressionId:     "1",
+	}, data.Bytes()
+}
+
+// serveGateways registers a storage provider with one gateway per response body.
+func serveGateways(t *testing.T, bodies ...[]byte) []*atomic.Int64 {
+	t.Helper()
+
+	provider := utils.StorageProvider{Id: 99, Name: "test"}
+	requests := make([]*atomic.Int64, 0, len(bodies))
+	for _, body := range bodies {
+		body, counter := body, new(atomic.Int64)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			counter.Add(1)
+			_, _ = w.Write(body)
+		}))
+		t.Cleanup(server.Close)
+
+		provider.Gateways = append(provider.Gateways, utils.Gateway{Url: server.URL})
+		requests = append(requests, counter)
+	}
+
+	if err := SetStorageProviders([]utils.StorageProvider{provider}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetStorageProviders(nil) })
+	return requests
+}
+
+func TestFetchBundleSkipsCorruptGateway(t *testing.T) {
+	bundle, data := testBundle(t, `[{"key":"1","value":{}}]`)
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	requests := serveGateways(t, corrupt, data)
+
+	if _, err := FetchBundle(bundle, nil); err == nil {
+		t.Fatal("expected checksum error")
+	}
+
+	// The retry goes straight to the second gateway
+	fetched, err := FetchBundle(bundle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(fetched, data) {
+		t.Fatal("fetched data differs")
+	}
+	if requests[0].Load() != 1 || requests[1].Load() != 1 {
+		t.Fatalf("expected one request per gateway, got %d and %d", requests[0].Load(), requests[1].Load())
+	}
+}
+
+func TestFetchBundleRetriesAllCorruptGateways(t *testing.T) {
+	bundle, data := testBundle(t, `[{"key":"1","value":{}}]`)
+	corrupt := append([]byte{}, data...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	requests := serveGateways(t, corrupt)
+
+	for i := 0; i < 3; i++ {
+		if _, err := FetchBundle(bundle, nil); err == nil {
+			t.Fatal("expected checksum error")
+		}
+	}
+	if requests[0].Load() != 3 {
+		t.Fatalf("expected 3 requests, got %d", requests[0].Load())
+	}
+}
+
+func TestOpenFromGatewayTimesOutStalledReads(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	body, err := openFromGateway(Gateway{Url: server.URL, Timeout: 50 * time.Millisecond}, "storage-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	if _, err := io.ReadAll(body); err == nil {
+		t.Fatal("expected stalled read to time out")
+	}
+}
+
+func TestOpenFromGatewayIgnoresSlowConsumer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("complete body"))
+	}))
+	defer server.Close()
+
+	body, err := openFromGateway(Gateway{Url: server.URL, Timeout: 50 * time.Millisecond}, "storage-id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+
+	// Converting the rows takes longer than the timeout, but no read stalls
+	buf := make([]byte, 4)
+	var data []byte
+	for {
+		time.Sleep(20 * time.Millisecond)
+		n, err := body.Read(buf)
+		data = append(data, buf[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if string(data) != "complete body" {
+		t.Fatalf("unexpected body %q", data)
+	}
+}
+
+func TestDownloadBundleReservesMemory(t *testing.T) {
+	bundle, data := testBundle(t, `[{"key":"1","value":"`+strings.Repeat("a", 10000)+`"}]`)
+	serveGateways(t, data)
+
+	memory := utils.NewMemoryGovernor("test", 1<<20)
+	var reservation *utils.MemoryReservation
+	var compressedSize, reservedSize int64
+	result, err := Base{}.DownloadAndConvertBundle(bundle, ExtraData{
+		ExtractedAt: time.Now(),
+		Reserve: func(size int64) (*utils.MemoryReservation, error) {
+			compressedSize = size
+			reservation, _ = memory.Reserve(context.Background(), memory.EstimateBundle(size))
+			return reservation, nil
+		},
+	}, RowSinkFunc(func(row DataRow) error {
+		reservedSize = reservation.Size()
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The estimate is reserved before the download and grows with the decompressed data
+	if compressedSize != int64(len(data)) {
+		t.Fatalf("got compressed size %d, want %d", compressedSize, len(data))
+	}
+	if reservedSize < result.UncompressedSize {
+		t.Fatalf("reserved %d bytes for %d decompressed bytes", reservedSize, result.UncompressedSize)
+	}
+	if reservation.Size() != 0 {
+		t.Fatal("reservation was not released")
+	}
+
+	// Further bundles are estimated with the observed expansion ratio
+	memory.ObserveBundle(result.CompressedSize, result.UncompressedSize)
+	if estimate := memory.EstimateBundle(result.CompressedSize); estimate != result.UncompressedSize {
+		t.Fatalf("got estimate %d, want %d", estimate, result.UncompressedSize)
+	}
+}
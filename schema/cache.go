@@ -0,0 +1,296 @@
+package schema
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BundleCache stores the compressed bundle data on disk, content-addressed by the data_hash
+// of the bundle. If the cache exceeds its max size, the least recently used bundles are removed.
+//
+// The size and usage order of the entries are kept in memory, the directory is only read when
+// the cache is used for the first time and by Prune and Stats. Entries added by other processes
+// are therefore only evicted after the next Prune.
+type BundleCache struct {
+	dir     string
+	maxSize int64
+
+	mu     sync.Mutex
+	loaded bool
+	size   int64
+	// Least recently used entries are at the front
+	lru   *list.List
+	index map[string]*list.Element
+}
+
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Size    int64
+	MaxSize int64
+}
+
+type cacheEntry struct {
+	path   string
+	size   int64
+	usedAt time.Time
+}
+
+var bundleCache *BundleCache
+
+// SetBundleCache enables the cache for all bundle downloads, nil disables it.
+func SetBundleCache(cache *BundleCache) {
+	bundleCache = cache
+}
+
+func NewBundleCache(dir string, maxSize int64) (*BundleCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache dir: %w", err)
+	}
+	return &BundleCache{dir: dir, maxSize: maxSize, lru: list.New(), index: make(map[string]*list.Element)}, nil
+}
+
+func (c *BundleCache) path(dataHash string) (string, error) {
+	if len(dataHash) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid data_hash %q", dataHash)
+	}
+	if _, err := hex.DecodeString(dataHash); err != nil {
+		return "", fmt.Errorf("invalid data_hash %q", dataHash)
+	}
+	return filepath.Join(c.dir, dataHash[:2], dataHash), nil
+}
+
+// Open returns a reader of the cached data. The data is not verified, callers have to
+// compare it with the data_hash and Remove the entry if it is corrupted.
+func (c *BundleCache) Open(dataHash string) (io.ReadCloser, bool) {
+	path, err := c.path(dataHash)
+	if err != nil {
+		return nil, false
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+
+	// The modification time keeps the usage order across restarts
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	c.mu.Lock()
+	if element, ok := c.index[path]; ok {
+		element.Value.(*cacheEntry).usedAt = now
+		c.lru.MoveToBack(element)
+	}
+	c.mu.Unlock()
+
+	return f, true
+}
+
+// Remove deletes a cache entry.
+func (c *BundleCache) Remove(dataHash string) {
+	path, err := c.path(dataHash)
+	if err != nil {
+		return
+	}
+	_ = os.Remove(path)
+
+	c.mu.Lock()
+	c.forget(path)
+	c.mu.Unlock()
+}
+
+// cacheWriter writes a new entry to a temporary file, so that readers never see partial data.
+type cacheWriter struct {
+	cache *BundleCache
+	tmp   *os.File
+	path  string
+}
+
+// Create starts a new cache entry, it is added to the cache once Commit is called.
+func (c *BundleCache) Create(dataHash string) (*cacheWriter, error) {
+	path, err := c.path(dataHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), dataHash+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	return &cacheWriter{cache: c, tmp: tmp, path: path}, nil
+}
+
+func (w *cacheWriter) Write(p []byte) (int, error) {
+	return w.tmp.Write(p)
+}
+
+// Commit adds the verified data to the cache and evicts old entries if the max size is exceeded.
+func (w *cacheWriter) Commit() error {
+	if err := w.tmp.Close(); err != nil {
+		_ = os.Remove(w.tmp.Name())
+		return err
+	}
+	info, err := os.Stat(w.tmp.Name())
+	if err != nil {
+		_ = os.Remove(w.tmp.Name())
+		return err
+	}
+	if err := os.Rename(w.tmp.Name(), w.path); err != nil {
+		_ = os.Remove(w.tmp.Name())
+		return err
+	}
+
+	c := w.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return fmt.Errorf("failed to read cache: %w", err)
+	}
+	c.forget(w.path)
+	c.add(cacheEntry{path: w.path, size: info.Size(), usedAt: time.Now()})
+
+	if c.maxSize > 0 {
+		if _, _, err := c.evict(c.maxSize); err != nil {
+			return fmt.Errorf("failed to evict cache entries: %w", err)
+		}
+	}
+	return nil
+}
+
+// Abort discards the data written so far.
+func (w *cacheWriter) Abort() {
+	_ = w.tmp.Close()
+	_ = os.Remove(w.tmp.Name())
+}
+
+// Prune reads the cache directory and removes the least recently used entries until
+// the cache is not larger than maxSize.
+func (c *BundleCache) Prune(maxSize int64) (removed int, freed int64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.scan(); err != nil {
+		return 0, 0, err
+	}
+	return c.evict(maxSize)
+}
+
+// Stats reads the cache directory and returns the number and size of the entries.
+func (c *BundleCache) Stats() (CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.scan(); err != nil {
+		return CacheStats{}, err
+	}
+	return CacheStats{
+		Dir:     c.dir,
+		Entries: c.lru.Len(),
+		Size:    c.size,
+		MaxSize: c.maxSize,
+	}, nil
+}
+
+// evict removes the least recently used entries of the index until the cache is not larger than maxSize.
+func (c *BundleCache) evict(maxSize int64) (removed int, freed int64, err error) {
+	for c.size > maxSize && c.lru.Len() > 0 {
+		entry := c.lru.Front().Value.(*cacheEntry)
+		if err := os.Remove(entry.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return removed, freed, err
+		}
+		c.forget(entry.path)
+		freed += entry.size
+		removed++
+	}
+	return removed, freed, nil
+}
+
+// load builds the index from the cache directory if it wasn't read yet.
+func (c *BundleCache) load() error {
+	if c.loaded {
+		return nil
+	}
+	return c.scan()
+}
+
+// scan replaces the index with the entries of the cache directory.
+func (c *BundleCache) scan() error {
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].usedAt.Before(entries[j].usedAt)
+	})
+
+	c.size = 0
+	c.lru.Init()
+	c.index = make(map[string]*list.Element, len(entries))
+	for _, entry := range entries {
+		c.add(entry)
+	}
+	c.loaded = true
+	return nil
+}
+
+func (c *BundleCache) add(entry cacheEntry) {
+	c.index[entry.path] = c.lru.PushBack(&entry)
+	c.size += entry.size
+}
+
+func (c *BundleCache) forget(path string) {
+	element, ok := c.index[path]
+	if !ok {
+		return
+	}
+	c.size -= element.Value.(*cacheEntry).size
+	c.lru.Remove(element)
+	delete(c.index, path)
+}
+
+func (c *BundleCache) entries() ([]cacheEntry, error) {
+	var entries []cacheEntry
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) == ".tmp" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			// The entry was removed in the meantime
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		entries = append(entries, cacheEntry{
+			path:   path,
+			size:   info.Size(),
+			usedAt: info.ModTime(),
+		})
+		return nil
+	})
+	return entries, err
+}
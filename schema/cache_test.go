@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func cacheTestHash(data string) string {
+	hash := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(hash[:])
+}
+
+func addToCache(t *testing.T, cache *BundleCache, data string) string {
+	t.Helper()
+
+	dataHash := cacheTestHash(data)
+	writer, err := cache.Create(dataHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return dataHash
+}
+
+func isCached(cache *BundleCache, dataHash string) bool {
+	body, ok := cache.Open(dataHash)
+	if ok {
+		_, _ = io.Copy(io.Discard, body)
+		_ = body.Close()
+	}
+	return ok
+}
+
+func TestBundleCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, err := NewBundleCache(t.TempDir(), 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := addToCache(t, cache, "first bundle")  // 12 bytes
+	second := addToCache(t, cache, "second bundl") // 12 bytes
+
+	// Using the first entry makes the second one the least recently used
+	if !isCached(cache, first) {
+		t.Fatal("first bundle is not cached")
+	}
+	third := addToCache(t, cache, "third bundle") // 12 bytes
+
+	if isCached(cache, second) {
+		t.Fatal("second bundle should have been evicted")
+	}
+	if !isCached(cache, first) || !isCached(cache, third) {
+		t.Fatal("first and third bundle should be cached")
+	}
+
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 2 || stats.Size != 24 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestBundleCacheRemove(t *testing.T) {
+	cache, err := NewBundleCache(t.TempDir(), 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dataHash := addToCache(t, cache, "bundle")
+	cache.Remove(dataHash)
+
+	if isCached(cache, dataHash) {
+		t.Fatal("bundle should have been removed")
+	}
+	if cache.size != 0 || cache.lru.Len() != 0 {
+		t.Fatalf("index still holds %d entries with %d bytes", cache.lru.Len(), cache.size)
+	}
+}
+
+func TestBundleCachePruneReadsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewBundleCache(dir, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addToCache(t, cache, "bundle")
+
+	// Entries of other processes are not part of the index until the directory is read again
+	external := cacheTestHash("external")
+	if err := os.MkdirAll(filepath.Join(dir, external[:2]), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, external[:2], external), []byte("external"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, freed, err := cache.Prune(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 2 || freed != int64(len("bundle")+len("external")) {
+		t.Fatalf("removed %d entries with %d bytes", removed, freed)
+	}
+}
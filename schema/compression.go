@@ -0,0 +1,46 @@
+package schema
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// Decompressor wraps the compressed bundle data with a reader returning the uncompressed data.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+var (
+	decompressorsMutex sync.RWMutex
+	decompressors      = map[string]Decompressor{
+		"0": func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+		"1": func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+)
+
+// RegisterDecompressor adds support for a compression_id of the KYVE protocol.
+func RegisterDecompressor(compressionId string, decompressor Decompressor) {
+	decompressorsMutex.Lock()
+	defer decompressorsMutex.Unlock()
+
+	decompressors[compressionId] = decompressor
+}
+
+// getDecompressor returns the decompressor for the compression_id of a bundle.
+// An unknown compression_id can't be resolved by retrying, therefore a permanent error is returned.
+func getDecompressor(compressionId string) (Decompressor, error) {
+	decompressorsMutex.RLock()
+	defer decompressorsMutex.RUnlock()
+
+	decompressor, ok := decompressors[compressionId]
+	if !ok {
+		return nil, utils.Permanent(fmt.Errorf("compression_id %q is not supported", compressionId))
+	}
+	return decompressor, nil
+}
@@ -0,0 +1,221 @@
+package schema
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Field number of the cosmos.msg.v1.signer message option, which names the signer fields of a message.
+const cosmosSignerOption = 11110000
+
+// signerFields are used for messages without the signer option, e.g. of chains with older protos.
+var signerFields = []protoreflect.Name{"signer", "sender", "from_address", "delegator_address", "validator_address", "authority", "voter", "depositor", "grantee", "creator"}
+
+// cosmosSDKProtos declares the messages of the Cosmos SDK which are decoded without a registry of the chain.
+//
+//go:embed protos/cosmos_sdk.textproto
+var cosmosSDKProtos []byte
+
+// cosmosSDKTypes parses the embedded messages once.
+var cosmosSDKTypes = sync.OnceValues(func() (*dynamicpb.Types, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := prototext.Unmarshal(cosmosSDKProtos, set); err != nil {
+		return nil, fmt.Errorf("invalid Cosmos SDK protos: %w", err)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(anypb.File_google_protobuf_any_proto))
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Cosmos SDK protos: %w", err)
+	}
+	return dynamicpb.NewTypes(files), nil
+})
+
+// ProtoRegistry resolves the messages of a chain from FileDescriptorSets and falls
+// back to the embedded Cosmos SDK messages.
+type ProtoRegistry struct {
+	// Searched in order, the Cosmos SDK messages are last
+	types []*dynamicpb.Types
+}
+
+// LoadProtoRegistry reads binary FileDescriptorSets, e.g. created with `buf build -o`. Files which
+// are contained in more than one set are only used once, so sets may share their dependencies.
+// Without paths the registry only contains the Cosmos SDK messages.
+func LoadProtoRegistry(paths []string) (*ProtoRegistry, error) {
+	sdkTypes, err := cosmosSDKTypes()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return &ProtoRegistry{types: []*dynamicpb.Types{sdkTypes}}, nil
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fileSet descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &fileSet); err != nil {
+			return nil, fmt.Errorf("invalid descriptor set %s: %w", path, err)
+		}
+		for _, file := range fileSet.File {
+			if seen[file.GetName()] {
+				continue
+			}
+			seen[file.GetName()] = true
+			set.File = append(set.File, file)
+		}
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor sets: %w", err)
+	}
+	return &ProtoRegistry{types: []*dynamicpb.Types{dynamicpb.NewTypes(files), sdkTypes}}, nil
+}
+
+// The Find methods make the registry a resolver of protojson, which needs them for the
+// messages nested in an Any, e.g. of authz.
+
+func (r *ProtoRegistry) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	for _, types := range r.types {
+		if messageType, err := types.FindMessageByName(name); err == nil {
+			return messageType, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r *ProtoRegistry) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	for _, types := range r.types {
+		if messageType, err := types.FindMessageByURL(url); err == nil {
+			return messageType, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r *ProtoRegistry) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	for _, types := range r.types {
+		if extensionType, err := types.FindExtensionByName(field); err == nil {
+			return extensionType, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+func (r *ProtoRegistry) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	for _, types := range r.types {
+		if extensionType, err := types.FindExtensionByNumber(message, field); err == nil {
+			return extensionType, nil
+		}
+	}
+	return nil, protoregistry.NotFound
+}
+
+// Decode returns the message as JSON with the proto field names and its first signer.
+func (r *ProtoRegistry) Decode(typeURL string, value []byte) (json.RawMessage, string, error) {
+	if r == nil {
+		return nil, "", fmt.Errorf("unknown message type %s", typeURL)
+	}
+	messageType, err := r.FindMessageByURL(typeURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown message type %s", typeURL)
+	}
+
+	message := messageType.New()
+	if err := proto.Unmarshal(value, message.Interface()); err != nil {
+		return nil, "", fmt.Errorf("invalid %s: %w", typeURL, err)
+	}
+	data, err := protojson.MarshalOptions{Resolver: r, UseProtoNames: true}.Marshal(message.Interface())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode %s: %w", typeURL, err)
+	}
+	return data, messageSigner(message), nil
+}
+
+func messageSigner(message protoreflect.Message) string {
+	descriptor := message.Descriptor()
+
+	names := signerOption(descriptor)
+	if len(names) == 0 {
+		names = signerFields
+	}
+	for _, name := range names {
+		field := descriptor.Fields().ByName(name)
+		if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+			continue
+		}
+		if signer := message.Get(field).String(); signer != "" {
+			return signer
+		}
+	}
+	return ""
+}
+
+// signerOption reads the cosmos.msg.v1.signer option, which is an
+// unknown field because the extension is not linked.
+func signerOption(descriptor protoreflect.MessageDescriptor) []protoreflect.Name {
+	options, ok := descriptor.Options().(*descriptorpb.MessageOptions)
+	if !ok || options == nil {
+		return nil
+	}
+
+	var names []protoreflect.Name
+	_ = protoFields(options.ProtoReflect().GetUnknown(), func(num protowire.Number, _ uint64, b []byte) error {
+		if num == cosmosSignerOption {
+			names = append(names, protoreflect.Name(b))
+		}
+		return nil
+	})
+	return names
+}
+
+// protoFields calls fn for every field of an encoded protobuf message,
+// v holds varints and b the bytes of length-delimited fields.
+func protoFields(data []byte, fn func(num protowire.Number, v uint64, b []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var v uint64
+		var b []byte
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if err := fn(num, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
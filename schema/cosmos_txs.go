@@ -0,0 +1,420 @@
+package schema
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// cosmosTx holds the fields of a cosmos.tx.v1beta1.Tx which are the same for all chains.
+type cosmosTx struct {
+	Messages      []cosmosAny
+	Memo          string
+	TimeoutHeight uint64
+	Fee           []cosmosCoin
+	GasLimit      uint64
+	FeePayer      string
+	FeeGranter    string
+}
+
+type cosmosAny struct {
+	TypeURL string
+	Value   []byte
+}
+
+type cosmosCoin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+// decodeCosmosTx decodes the envelope of a transaction (TxRaw, TxBody, AuthInfo and Fee).
+func decodeCosmosTx(data []byte) (*cosmosTx, error) {
+	tx := &cosmosTx{}
+	var body, authInfo []byte
+	err := protoFields(data, func(num protowire.Number, _ uint64, b []byte) error {
+		// A TxRaw only has the body, the auth info and the signatures, other encodings,
+		// e.g. amino, can be valid protobuf by chance but have other fields
+		if num < 1 || num > 3 || b == nil {
+			return fmt.Errorf("unexpected field %d", num)
+		}
+		switch num {
+		case 1:
+			body = b
+		case 2:
+			authInfo = b
+		}
+		return nil
+	})
+	if err == nil && body == nil {
+		err = fmt.Errorf("missing body")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx: %w", err)
+	}
+
+	err = protoFields(body, func(num protowire.Number, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			var message cosmosAny
+			err := protoFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+				switch num {
+				case 1:
+					message.TypeURL = string(b)
+				case 2:
+					message.Value = b
+				}
+				return nil
+			})
+			tx.Messages = append(tx.Messages, message)
+			return err
+		case 2:
+			tx.Memo = string(b)
+		case 3:
+			tx.TimeoutHeight = v
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid tx body: %w", err)
+	}
+
+	err = protoFields(authInfo, func(num protowire.Number, _ uint64, b []byte) error {
+		if num != 2 {
+			return nil
+		}
+		return protoFields(b, func(num protowire.Number, v uint64, b []byte) error {
+			switch num {
+			case 1:
+				var coin cosmosCoin
+				err := protoFields(b, func(num protowire.Number, _ uint64, b []byte) error {
+					switch num {
+					case 1:
+						coin.Denom = string(b)
+					case 2:
+						coin.Amount = string(b)
+					}
+					return nil
+				})
+				tx.Fee = append(tx.Fee, coin)
+				return err
+			case 2:
+				tx.GasLimit = v
+			case 3:
+				tx.FeePayer = string(b)
+			case 4:
+				tx.FeeGranter = string(b)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth info: %w", err)
+	}
+	return tx, nil
+}
+
+// cosmosBlock is the block of a /block response, which is nested in older runtime versions.
+type cosmosBlock struct {
+	Block  *cosmosBlock `json:"block"`
+	Header struct {
+		Time time.Time `json:"time"`
+	} `json:"header"`
+	Data struct {
+		Txs [][]byte `json:"txs"`
+	} `json:"data"`
+}
+
+type cosmosTxResult struct {
+	Code      json.Number `json:"code"`
+	Codespace string      `json:"codespace"`
+	GasWanted json.Number `json:"gas_wanted"`
+	GasUsed   json.Number `json:"gas_used"`
+}
+
+type CosmosTxsItem struct {
+	Key   string `json:"key"`
+	Value struct {
+		Block        cosmosBlock `json:"block"`
+		BlockResults struct {
+			TxsResults []cosmosTxResult `json:"txs_results"`
+		} `json:"block_results"`
+	} `json:"value"`
+}
+
+func (i CosmosTxsItem) itemKey() string {
+	return i.Key
+}
+
+type CosmosTxRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	height            int64
+	tx_index          int64
+	tx_hash           string
+	block_time        time.Time
+	signer            string
+	memo              string
+	fee               json.RawMessage
+	gas_limit         int64
+	fee_payer         string
+	fee_granter       string
+	timeout_height    int64
+	message_count     int64
+	result            *cosmosTxResult
+	decode_error      string
+	bundle_id         int64
+}
+
+func (t CosmosTxRow) TableName() string {
+	return "txs"
+}
+
+func (t CosmosTxRow) Values() []any {
+	// The envelope columns are NULL for transactions which couldn't be decoded
+	var fee, gasLimit, timeoutHeight, messageCount any
+	if t.decode_error == "" {
+		fee, gasLimit, timeoutHeight, messageCount = t.fee, t.gas_limit, t.timeout_height, t.message_count
+	}
+	var code, codespace, gasWanted, gasUsed any
+	if t.result != nil {
+		code = integer(t.result.Code.String())
+		codespace = optional(t.result.Codespace)
+		gasWanted = integer(t.result.GasWanted.String())
+		gasUsed = integer(t.result.GasUsed.String())
+	}
+	return []any{
+		rawId(t.TableName(), t.height, t.tx_index),
+		t._dlt_extracted_at,
+		t.height,
+		t.tx_index,
+		t.tx_hash,
+		optionalTime(t.block_time),
+		optional(t.signer),
+		optional(t.memo),
+		fee,
+		gasLimit,
+		optional(t.fee_payer),
+		optional(t.fee_granter),
+		timeoutHeight,
+		messageCount,
+		code,
+		codespace,
+		gasWanted,
+		gasUsed,
+		optional(t.decode_error),
+		t.bundle_id,
+	}
+}
+
+type CosmosMessageRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	height            int64
+	tx_index          int64
+	msg_index         int64
+	tx_hash           string
+	block_time        time.Time
+	type_url          string
+	signer            string
+	message           json.RawMessage
+	raw_value         []byte
+	decode_error      string
+	bundle_id         int64
+}
+
+func (t CosmosMessageRow) TableName() string {
+	return "messages"
+}
+
+func (t CosmosMessageRow) Values() []any {
+	var message, rawValue any
+	if t.message != nil {
+		message = t.message
+	} else {
+		rawValue = t.raw_value
+	}
+	return []any{
+		rawId(t.TableName(), t.height, t.tx_index, t.msg_index),
+		t._dlt_extracted_at,
+		t.height,
+		t.tx_index,
+		t.msg_index,
+		t.tx_hash,
+		optionalTime(t.block_time),
+		t.type_url,
+		optional(t.signer),
+		message,
+		rawValue,
+		optional(t.decode_error),
+		t.bundle_id,
+	}
+}
+
+// CosmosTxs decodes the transactions of Tendermint blocks, the messages
+// are decoded with the types of the registry.
+type CosmosTxs struct {
+	Registry *ProtoRegistry
+}
+
+func (t CosmosTxs) Tables() []Table {
+	return []Table{
+		{
+			Name: "txs",
+			Columns: columns(
+				Column{Name: "height", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_index", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_hash", Type: StringColumn, Required: true},
+				Column{Name: "block_time", Type: TimestampColumn},
+				Column{Name: "signer", Type: StringColumn},
+				Column{Name: "memo", Type: StringColumn},
+				Column{Name: "fee", Type: JSONColumn},
+				Column{Name: "gas_limit", Type: IntegerColumn},
+				Column{Name: "fee_payer", Type: StringColumn},
+				Column{Name: "fee_granter", Type: StringColumn},
+				Column{Name: "timeout_height", Type: IntegerColumn},
+				Column{Name: "message_count", Type: IntegerColumn},
+				Column{Name: "code", Type: IntegerColumn},
+				Column{Name: "codespace", Type: StringColumn},
+				Column{Name: "gas_wanted", Type: IntegerColumn},
+				Column{Name: "gas_used", Type: IntegerColumn},
+				Column{Name: "decode_error", Type: StringColumn},
+			),
+			PrimaryKey:       []string{"height", "tx_index"},
+			TimePartitioning: &bigquery.TimePartitioning{Field: "block_time", Type: bigquery.DayPartitioningType},
+			Clustering:       &bigquery.Clustering{Fields: []string{"signer", "height"}},
+		},
+		{
+			Name: "messages",
+			Columns: columns(
+				Column{Name: "height", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_index", Type: IntegerColumn, Required: true},
+				Column{Name: "msg_index", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_hash", Type: StringColumn, Required: true},
+				Column{Name: "block_time", Type: TimestampColumn},
+				Column{Name: "type_url", Type: StringColumn, Required: true},
+				Column{Name: "signer", Type: StringColumn},
+				Column{Name: "message", Type: JSONColumn},
+				Column{Name: "raw_value", Type: BytesColumn},
+				Column{Name: "decode_error", Type: StringColumn},
+			),
+			PrimaryKey:       []string{"height", "tx_index", "msg_index"},
+			TimePartitioning: &bigquery.TimePartitioning{Field: "block_time", Type: bigquery.DayPartitioningType},
+			Clustering:       &bigquery.Clustering{Fields: []string{"type_url", "signer", "height"}},
+		},
+	}
+}
+
+func (t CosmosTxs) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
+	bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
+
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem CosmosTxsItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			height, err := strconv.ParseInt(kyveItem.Key, 10, 64)
+			if err != nil {
+				return utils.Permanent(fmt.Errorf("invalid height %q: %w", kyveItem.Key, err))
+			}
+
+			block := &kyveItem.Value.Block
+			for block.Block != nil {
+				block = block.Block
+			}
+			results := kyveItem.Value.BlockResults.TxsResults
+
+			for index, txBytes := range block.Data.Txs {
+				hash := sha256.Sum256(txBytes)
+				row := CosmosTxRow{
+					_dlt_extracted_at: extra.ExtractedAt,
+					height:            height,
+					tx_index:          int64(index),
+					tx_hash:           strings.ToUpper(hex.EncodeToString(hash[:])),
+					block_time:        block.Header.Time,
+					bundle_id:         int64(bundleId),
+				}
+				if index < len(results) {
+					row.result = &results[index]
+				}
+
+				// Transactions which are no protobuf transactions, e.g. amino encoded ones of
+				// old chains, are loaded without their envelope and messages
+				tx, err := decodeCosmosTx(txBytes)
+				if err != nil {
+					row.decode_error = err.Error()
+					if err := sink.Write(row); err != nil {
+						return err
+					}
+					continue
+				}
+
+				fee, err := json.Marshal(tx.Fee)
+				if err != nil {
+					return err
+				}
+				row.memo = tx.Memo
+				row.fee = fee
+				row.gas_limit = int64(tx.GasLimit)
+				row.fee_payer = tx.FeePayer
+				row.fee_granter = tx.FeeGranter
+				row.timeout_height = int64(tx.TimeoutHeight)
+				row.message_count = int64(len(tx.Messages))
+
+				messages := make([]DataRow, 0, len(tx.Messages))
+				for msgIndex, msg := range tx.Messages {
+					messageRow := CosmosMessageRow{
+						_dlt_extracted_at: extra.ExtractedAt,
+						height:            height,
+						tx_index:          int64(index),
+						msg_index:         int64(msgIndex),
+						tx_hash:           row.tx_hash,
+						block_time:        block.Header.Time,
+						type_url:          msg.TypeURL,
+						raw_value:         msg.Value,
+						bundle_id:         int64(bundleId),
+					}
+					messageRow.message, messageRow.signer, err = t.Registry.Decode(msg.TypeURL, msg.Value)
+					if err != nil {
+						messageRow.decode_error = err.Error()
+					}
+					// The first signer pays the fees and signs the transaction
+					if row.signer == "" {
+						row.signer = messageRow.signer
+					}
+					messages = append(messages, messageRow)
+				}
+				if err := sink.Write(row); err != nil {
+					return err
+				}
+				for _, messageRow := range messages {
+					if err := sink.Write(messageRow); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		CompressedSize:   downloadResult.CompressedSize,
+		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
+	}, nil
+}
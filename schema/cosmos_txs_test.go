@@ -0,0 +1,299 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+const (
+	testAlice     = "cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"
+	testBob       = "cosmos1zg69v7ys40x77y352eufp27daufrg4nc5zsz8v"
+	testValidator = "cosmosvaloper1zg69v7ys40x77y352eufp27daufrg4ncz8dhlr"
+)
+
+// cosmosTxFixtures returns the encoded transactions of the testdata by name: protobuf transactions
+// encoded like the Cosmos SDK and an amino StdTx of cosmoshub-3.
+func cosmosTxFixtures(t *testing.T) map[string][]byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "cosmos", "txs.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		t.Fatal(err)
+	}
+	txs := make(map[string][]byte, len(encoded))
+	for name, tx := range encoded {
+		if txs[name], err = base64.StdEncoding.DecodeString(tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return txs
+}
+
+func TestDecodeCosmosTx(t *testing.T) {
+	txs := cosmosTxFixtures(t)
+
+	tests := []struct {
+		name string
+		tx   []byte
+		want *cosmosTx
+		err  bool
+	}{
+		{
+			name: "send",
+			tx:   txs["send"],
+			want: &cosmosTx{
+				Messages: []cosmosAny{{TypeURL: "/cosmos.bank.v1beta1.MsgSend"}},
+				Memo:     "kyve dlt",
+				Fee:      []cosmosCoin{{Denom: "uatom", Amount: "5000"}},
+				GasLimit: 200000,
+			},
+		},
+		{
+			name: "delegate with fee payer",
+			tx:   txs["delegate"],
+			want: &cosmosTx{
+				Messages: []cosmosAny{
+					{TypeURL: "/cosmos.staking.v1beta1.MsgDelegate"},
+					{TypeURL: "/cosmos.distribution.v1beta1.MsgWithdrawDelegatorReward"},
+				},
+				TimeoutHeight: 12345,
+				Fee:           []cosmosCoin{{Denom: "uatom", Amount: "7500"}},
+				GasLimit:      300000,
+				FeePayer:      testAlice,
+				FeeGranter:    testBob,
+			},
+		},
+		{
+			name: "exec",
+			tx:   txs["exec"],
+			want: &cosmosTx{
+				Messages: []cosmosAny{
+					{TypeURL: "/cosmos.authz.v1beta1.MsgExec"},
+					{TypeURL: "/dlt.test.v1.MsgPing"},
+					{TypeURL: "/unknown.v1.MsgUnknown"},
+				},
+				Fee:      []cosmosCoin{{Denom: "uatom", Amount: "2000"}},
+				GasLimit: 100000,
+			},
+		},
+		{name: "amino", tx: txs["amino"], err: true},
+		{name: "empty", tx: []byte{}, err: true},
+		{name: "json", tx: []byte(`{"body":{}}`), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, err := decodeCosmosTx(tt.tx)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", tx)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The message values are checked by the registry tests
+			for i := range tx.Messages {
+				tx.Messages[i].Value = nil
+			}
+			if got, want := fmt.Sprintf("%+v", *tx), fmt.Sprintf("%+v", *tt.want); got != want {
+				t.Fatalf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestProtoRegistryDecode(t *testing.T) {
+	txs := cosmosTxFixtures(t)
+	messages := make(map[string][]byte)
+	for _, name := range []string{"send", "delegate", "exec"} {
+		tx, err := decodeCosmosTx(txs[name])
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, message := range tx.Messages {
+			messages[message.TypeURL] = message.Value
+		}
+	}
+
+	sdkRegistry, err := LoadProtoRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainRegistry, err := LoadProtoRegistry([]string{filepath.Join("testdata", "cosmos", "registry.binpb")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		registry *ProtoRegistry
+		typeURL  string
+		message  string
+		signer   string
+		err      bool
+	}{
+		{
+			name:     "sdk send",
+			registry: sdkRegistry,
+			typeURL:  "/cosmos.bank.v1beta1.MsgSend",
+			message:  `{"from_address":"` + testAlice + `","to_address":"` + testBob + `","amount":[{"denom":"uatom","amount":"1000000"}]}`,
+			signer:   testAlice,
+		},
+		{
+			name:     "sdk delegate",
+			registry: sdkRegistry,
+			typeURL:  "/cosmos.staking.v1beta1.MsgDelegate",
+			message:  `{"delegator_address":"` + testAlice + `","validator_address":"` + testValidator + `","amount":{"denom":"uatom","amount":"250000"}}`,
+			signer:   testAlice,
+		},
+		{
+			name:     "sdk exec with nested vote",
+			registry: sdkRegistry,
+			typeURL:  "/cosmos.authz.v1beta1.MsgExec",
+			message:  `{"grantee":"` + testBob + `","msgs":[{"@type":"/cosmos.gov.v1beta1.MsgVote","proposal_id":"42","voter":"` + testAlice + `","option":"VOTE_OPTION_YES"}]}`,
+			signer:   testBob,
+		},
+		{name: "chain message without registry", registry: sdkRegistry, typeURL: "/dlt.test.v1.MsgPing", err: true},
+		{
+			name:     "chain message",
+			registry: chainRegistry,
+			typeURL:  "/dlt.test.v1.MsgPing",
+			message:  `{"creator":"` + testBob + `","count":"3"}`,
+			signer:   testBob,
+		},
+		{
+			name:     "sdk message with chain registry",
+			registry: chainRegistry,
+			typeURL:  "/cosmos.bank.v1beta1.MsgSend",
+			message:  `{"from_address":"` + testAlice + `","to_address":"` + testBob + `","amount":[{"denom":"uatom","amount":"1000000"}]}`,
+			signer:   testAlice,
+		},
+		{name: "unknown message", registry: chainRegistry, typeURL: "/unknown.v1.MsgUnknown", err: true},
+		{name: "nil registry", registry: nil, typeURL: "/cosmos.bank.v1beta1.MsgSend", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, signer, err := tt.registry.Decode(tt.typeURL, messages[tt.typeURL])
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", message)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := compactJSON(t, message); got != tt.message {
+				t.Fatalf("got message %s, want %s", got, tt.message)
+			}
+			if signer != tt.signer {
+				t.Fatalf("got signer %q, want %q", signer, tt.signer)
+			}
+		})
+	}
+}
+
+// compactJSON removes the random whitespace of protojson.
+func compactJSON(t *testing.T, data []byte) string {
+	t.Helper()
+
+	var value json.RawMessage
+	if err := json.Unmarshal(data, &value); err != nil {
+		t.Fatal(err)
+	}
+	compact, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(compact)
+}
+
+func cosmosTxsItems(key string, txs ...[]byte) string {
+	encoded := make([]string, 0, len(txs))
+	results := make([]string, 0, len(txs))
+	for i, tx := range txs {
+		encoded = append(encoded, `"`+base64.StdEncoding.EncodeToString(tx)+`"`)
+		results = append(results, fmt.Sprintf(`{"code":%d,"gas_wanted":"200000","gas_used":"%d"}`, i, 100000+i))
+	}
+	return fmt.Sprintf(`[{"key":"%s","value":{"block":{"block":{"header":{"time":"2024-01-01T00:00:00Z"},"data":{"txs":[%s]}}},"block_results":{"txs_results":[%s]}}}]`,
+		key, strings.Join(encoded, ","), strings.Join(results, ","))
+}
+
+func TestCosmosTxsConversion(t *testing.T) {
+	txs := cosmosTxFixtures(t)
+	registry, err := LoadProtoRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := convertItems(t, CosmosTxs{Registry: registry}, cosmosTxsItems("100", txs["send"], txs["amino"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows["txs"]) != 2 || len(rows["messages"]) != 1 {
+		t.Fatalf("unexpected rows: %d txs, %d messages", len(rows["txs"]), len(rows["messages"]))
+	}
+
+	assertValues(t, rows["txs"][0], map[string]string{
+		"height":        "100",
+		"tx_index":      "0",
+		"block_time":    "2024-01-01T00:00:00Z",
+		"signer":        testAlice,
+		"memo":          "kyve dlt",
+		"fee":           `[{"denom":"uatom","amount":"5000"}]`,
+		"gas_limit":     "200000",
+		"message_count": "1",
+		"code":          "0",
+		"gas_used":      "100000",
+		"decode_error":  "",
+	})
+	assertValues(t, rows["messages"][0], map[string]string{
+		"tx_index":     "0",
+		"msg_index":    "0",
+		"type_url":     "/cosmos.bank.v1beta1.MsgSend",
+		"signer":       testAlice,
+		"raw_value":    "",
+		"decode_error": "",
+	})
+	// Amino transactions are loaded without their envelope and messages
+	assertValues(t, rows["txs"][1], map[string]string{
+		"tx_index":      "1",
+		"signer":        "",
+		"fee":           "",
+		"message_count": "",
+		"code":          "1",
+		"gas_used":      "100001",
+	})
+	if csvValue(rows["txs"][1]["decode_error"]) == "" {
+		t.Fatal("expected a decode error of the amino transaction")
+	}
+}
+
+func TestCosmosTxsInvalidHeight(t *testing.T) {
+	registry, err := LoadProtoRegistry(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = convertItems(t, CosmosTxs{Registry: registry}, cosmosTxsItems("latest"))
+	var permanentErr *utils.PermanentError
+	if !errors.As(err, &permanentErr) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
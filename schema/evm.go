@@ -0,0 +1,537 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// evmQuantity is a number of an EVM node, which is encoded differently depending
+// on the client: as JSON number, decimal or hex string or as ethers BigNumber object.
+type evmQuantity struct {
+	value *big.Int
+}
+
+func (q *evmQuantity) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var raw string
+	switch data[0] {
+	case '{':
+		var object struct {
+			Hex       string `json:"hex"`
+			LegacyHex string `json:"_hex"`
+		}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		raw = object.Hex + object.LegacyHex
+	case '"':
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+	default:
+		raw = string(data)
+	}
+
+	if raw == "" {
+		return nil
+	}
+
+	value, ok := new(big.Int), false
+	if strings.HasPrefix(raw, "0x") || strings.HasPrefix(raw, "0X") {
+		if raw == "0x" || raw == "0X" {
+			value, ok = value.SetInt64(0), true
+		} else {
+			value, ok = value.SetString(raw[2:], 16)
+		}
+	} else {
+		value, ok = value.SetString(raw, 10)
+	}
+	if !ok {
+		return fmt.Errorf("invalid quantity %q", raw)
+	}
+	q.value = value
+	return nil
+}
+
+// String returns the decimal value, or an empty string which is loaded as NULL.
+func (q evmQuantity) String() string {
+	if q.value == nil {
+		return ""
+	}
+	return q.value.String()
+}
+
+// integer returns the value as int64, or nil if it is missing or too large.
+func (q evmQuantity) integer() any {
+	if q.value == nil || !q.value.IsInt64() {
+		return nil
+	}
+	return q.value.Int64()
+}
+
+// maxBigNumeric is the largest integer of a BigQuery BIGNUMERIC, a 256-bit integer scaled by 10^38.
+var maxBigNumeric = new(big.Int).Quo(
+	new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(1)),
+	new(big.Int).Exp(big.NewInt(10), big.NewInt(38), nil),
+)
+
+// numeric returns the value, or nil if it is missing. The range is checked by checkNumeric.
+func (q evmQuantity) numeric() any {
+	if q.value == nil {
+		return nil
+	}
+	return q.value
+}
+
+// checkNumeric returns an error if the value of the column exceeds the BIGNUMERIC range.
+func checkNumeric(column string, q evmQuantity) error {
+	if q.value != nil && q.value.CmpAbs(maxBigNumeric) > 0 {
+		return fmt.Errorf("%s %s exceeds the BIGNUMERIC range", column, q.value)
+	}
+	return nil
+}
+
+// timestamp interprets the value as unix time in seconds.
+func (q evmQuantity) timestamp() any {
+	if q.value == nil {
+		return nil
+	}
+	return time.Unix(q.value.Int64(), 0).UTC()
+}
+
+type EvmBlock struct {
+	Number        evmQuantity      `json:"number"`
+	Hash          string           `json:"hash"`
+	ParentHash    string           `json:"parentHash"`
+	Timestamp     evmQuantity      `json:"timestamp"`
+	Miner         string           `json:"miner"`
+	GasLimit      evmQuantity      `json:"gasLimit"`
+	GasUsed       evmQuantity      `json:"gasUsed"`
+	BaseFeePerGas evmQuantity      `json:"baseFeePerGas"`
+	ExtraData     string           `json:"extraData"`
+	Transactions  []EvmTransaction `json:"transactions"`
+	Receipts      []EvmReceipt     `json:"receipts"`
+}
+
+type EvmTransaction struct {
+	Hash             string      `json:"hash"`
+	TransactionIndex evmQuantity `json:"transactionIndex"`
+	From             string      `json:"from"`
+	To               string      `json:"to"`
+	Value            evmQuantity `json:"value"`
+	Gas              evmQuantity `json:"gas"`
+	GasLimit         evmQuantity `json:"gasLimit"`
+	GasPrice         evmQuantity `json:"gasPrice"`
+	Nonce            evmQuantity `json:"nonce"`
+	Input            string      `json:"input"`
+	Data             string      `json:"data"`
+}
+
+// UnmarshalJSON also accepts blocks which only contain the transaction hashes.
+func (t *EvmTransaction) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &t.Hash)
+	}
+	type transaction EvmTransaction
+	return json.Unmarshal(data, (*transaction)(t))
+}
+
+type EvmReceipt struct {
+	TransactionHash   string      `json:"transactionHash"`
+	GasUsed           evmQuantity `json:"gasUsed"`
+	EffectiveGasPrice evmQuantity `json:"effectiveGasPrice"`
+	Status            evmQuantity `json:"status"`
+	ContractAddress   string      `json:"contractAddress"`
+	Logs              []EvmLog    `json:"logs"`
+}
+
+type EvmLog struct {
+	Address          string      `json:"address"`
+	Topics           []string    `json:"topics"`
+	Data             string      `json:"data"`
+	LogIndex         evmQuantity `json:"logIndex"`
+	TransactionIndex evmQuantity `json:"transactionIndex"`
+	TransactionHash  string      `json:"transactionHash"`
+}
+
+type EvmItem struct {
+	Key   string   `json:"key"`
+	Value EvmBlock `json:"value"`
+}
+
+func (i EvmItem) itemKey() string {
+	return i.Key
+}
+
+// UnmarshalJSON accepts the block either directly as value or
+// together with the receipts as {"block": ..., "receipts": [...]}.
+func (i *EvmItem) UnmarshalJSON(data []byte) error {
+	var item struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return err
+	}
+	i.Key = item.Key
+
+	var wrapped struct {
+		Block    *EvmBlock    `json:"block"`
+		Receipts []EvmReceipt `json:"receipts"`
+	}
+	if err := json.Unmarshal(item.Value, &wrapped); err != nil {
+		return err
+	}
+	if wrapped.Block != nil {
+		i.Value = *wrapped.Block
+		if wrapped.Receipts != nil {
+			i.Value.Receipts = wrapped.Receipts
+		}
+		return nil
+	}
+	return json.Unmarshal(item.Value, &i.Value)
+}
+
+type EvmBlockRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	block_number      int64
+	hash              string
+	parent_hash       string
+	timestamp         evmQuantity
+	miner             string
+	gas_limit         evmQuantity
+	gas_used          evmQuantity
+	base_fee_per_gas  evmQuantity
+	extra_data        string
+	transaction_count int64
+	bundle_id         int64
+}
+
+func (t EvmBlockRow) TableName() string {
+	return "blocks"
+}
+
+func (t EvmBlockRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.block_number),
+		t._dlt_extracted_at,
+		t.block_number,
+		optional(t.hash),
+		optional(t.parent_hash),
+		t.timestamp.timestamp(),
+		optional(t.miner),
+		t.gas_limit.integer(),
+		t.gas_used.integer(),
+		t.base_fee_per_gas.numeric(),
+		optional(t.extra_data),
+		t.transaction_count,
+		t.bundle_id,
+	}
+}
+
+type EvmTransactionRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	block_number      int64
+	tx_index          int64
+	hash              string
+	block_hash        string
+	block_timestamp   evmQuantity
+	from              string
+	to                string
+	value             evmQuantity
+	gas               evmQuantity
+	gas_price         evmQuantity
+	gas_used          evmQuantity
+	nonce             evmQuantity
+	status            evmQuantity
+	contract_address  string
+	input             string
+	bundle_id         int64
+}
+
+func (t EvmTransactionRow) TableName() string {
+	return "transactions"
+}
+
+func (t EvmTransactionRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.block_number, t.tx_index),
+		t._dlt_extracted_at,
+		t.block_number,
+		t.tx_index,
+		optional(t.hash),
+		optional(t.block_hash),
+		t.block_timestamp.timestamp(),
+		optional(t.from),
+		optional(t.to),
+		t.value.numeric(),
+		t.gas.integer(),
+		t.gas_price.numeric(),
+		t.gas_used.integer(),
+		t.nonce.integer(),
+		t.status.integer(),
+		optional(t.contract_address),
+		optional(t.input),
+		t.bundle_id,
+	}
+}
+
+type EvmLogRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	block_number      int64
+	log_index         int64
+	tx_index          evmQuantity
+	tx_hash           string
+	block_hash        string
+	block_timestamp   evmQuantity
+	address           string
+	topics            []string
+	data              string
+	bundle_id         int64
+}
+
+func (t EvmLogRow) TableName() string {
+	return "logs"
+}
+
+func (t EvmLogRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.block_number, t.log_index),
+		t._dlt_extracted_at,
+		t.block_number,
+		t.log_index,
+		t.tx_index.integer(),
+		optional(t.tx_hash),
+		optional(t.block_hash),
+		t.block_timestamp.timestamp(),
+		optional(t.address),
+		repeated(t.topics),
+		optional(t.data),
+		t.bundle_id,
+	}
+}
+
+type Evm struct{}
+
+// Tables returns the blocks first, because every item produces a block
+// row, so that the progress can be tracked in the blocks table.
+func (t Evm) Tables() []Table {
+	partitioning := func(field string) *bigquery.TimePartitioning {
+		return &bigquery.TimePartitioning{Field: field, Type: bigquery.DayPartitioningType}
+	}
+
+	return []Table{
+		{
+			Name: "blocks",
+			Columns: columns(
+				Column{Name: "block_number", Type: IntegerColumn, Required: true},
+				Column{Name: "hash", Type: StringColumn},
+				Column{Name: "parent_hash", Type: StringColumn},
+				Column{Name: "timestamp", Type: TimestampColumn},
+				Column{Name: "miner", Type: StringColumn},
+				Column{Name: "gas_limit", Type: IntegerColumn},
+				Column{Name: "gas_used", Type: IntegerColumn},
+				Column{Name: "base_fee_per_gas", Type: BigNumericColumn},
+				Column{Name: "extra_data", Type: StringColumn},
+				Column{Name: "transaction_count", Type: IntegerColumn},
+			),
+			PrimaryKey:       []string{"block_number"},
+			TimePartitioning: partitioning("timestamp"),
+			Clustering:       &bigquery.Clustering{Fields: []string{"block_number"}},
+		},
+		{
+			Name: "transactions",
+			Columns: columns(
+				Column{Name: "block_number", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_index", Type: IntegerColumn, Required: true},
+				Column{Name: "hash", Type: StringColumn},
+				Column{Name: "block_hash", Type: StringColumn},
+				Column{Name: "block_timestamp", Type: TimestampColumn},
+				Column{Name: "from", Type: StringColumn},
+				Column{Name: "to", Type: StringColumn},
+				Column{Name: "value", Type: BigNumericColumn},
+				Column{Name: "gas", Type: IntegerColumn},
+				Column{Name: "gas_price", Type: BigNumericColumn},
+				Column{Name: "gas_used", Type: IntegerColumn},
+				Column{Name: "nonce", Type: IntegerColumn},
+				Column{Name: "status", Type: IntegerColumn},
+				Column{Name: "contract_address", Type: StringColumn},
+				Column{Name: "input", Type: StringColumn},
+			),
+			PrimaryKey:       []string{"block_number", "tx_index"},
+			TimePartitioning: partitioning("block_timestamp"),
+			Clustering:       &bigquery.Clustering{Fields: []string{"from", "to"}},
+		},
+		{
+			Name: "logs",
+			Columns: columns(
+				Column{Name: "block_number", Type: IntegerColumn, Required: true},
+				Column{Name: "log_index", Type: IntegerColumn, Required: true},
+				Column{Name: "tx_index", Type: IntegerColumn},
+				Column{Name: "tx_hash", Type: StringColumn},
+				Column{Name: "block_hash", Type: StringColumn},
+				Column{Name: "block_timestamp", Type: TimestampColumn},
+				Column{Name: "address", Type: StringColumn},
+				Column{Name: "topics", Type: StringColumn, Repeated: true},
+				Column{Name: "data", Type: StringColumn},
+			),
+			PrimaryKey:       []string{"block_number", "log_index"},
+			TimePartitioning: partitioning("block_timestamp"),
+			Clustering:       &bigquery.Clustering{Fields: []string{"address"}},
+		},
+	}
+}
+
+func (t Evm) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
+	bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
+
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem EvmItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			block := kyveItem.Value
+			blockNumber, ok := block.Number.integer().(int64)
+			if !ok {
+				number, err := strconv.ParseInt(kyveItem.Key, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid block number %q: %w", kyveItem.Key, err)
+				}
+				blockNumber = number
+			}
+
+			if err := checkNumeric("base_fee_per_gas", block.BaseFeePerGas); err != nil {
+				return utils.Permanent(fmt.Errorf("block %d: %w", blockNumber, err))
+			}
+			if err := sink.Write(EvmBlockRow{
+				_dlt_extracted_at: extra.ExtractedAt,
+				block_number:      blockNumber,
+				hash:              block.Hash,
+				parent_hash:       block.ParentHash,
+				timestamp:         block.Timestamp,
+				miner:             block.Miner,
+				gas_limit:         block.GasLimit,
+				gas_used:          block.GasUsed,
+				base_fee_per_gas:  block.BaseFeePerGas,
+				extra_data:        block.ExtraData,
+				transaction_count: int64(len(block.Transactions)),
+				bundle_id:         int64(bundleId),
+			}); err != nil {
+				return err
+			}
+
+			receipts := make(map[string]EvmReceipt, len(block.Receipts))
+			for _, receipt := range block.Receipts {
+				receipts[receipt.TransactionHash] = receipt
+			}
+
+			for index, tx := range block.Transactions {
+				txIndex, ok := tx.TransactionIndex.integer().(int64)
+				if !ok {
+					txIndex = int64(index)
+				}
+				gas := tx.Gas
+				if gas.value == nil {
+					gas = tx.GasLimit
+				}
+				input := tx.Input
+				if input == "" {
+					input = tx.Data
+				}
+
+				row := EvmTransactionRow{
+					_dlt_extracted_at: extra.ExtractedAt,
+					block_number:      blockNumber,
+					tx_index:          txIndex,
+					hash:              tx.Hash,
+					block_hash:        block.Hash,
+					block_timestamp:   block.Timestamp,
+					from:              tx.From,
+					to:                tx.To,
+					value:             tx.Value,
+					gas:               gas,
+					gas_price:         tx.GasPrice,
+					nonce:             tx.Nonce,
+					input:             input,
+					bundle_id:         int64(bundleId),
+				}
+				if receipt, ok := receipts[tx.Hash]; ok {
+					row.gas_used = receipt.GasUsed
+					row.status = receipt.Status
+					row.contract_address = receipt.ContractAddress
+					if receipt.EffectiveGasPrice.value != nil {
+						row.gas_price = receipt.EffectiveGasPrice
+					}
+				}
+				if err := errors.Join(checkNumeric("value", row.value), checkNumeric("gas_price", row.gas_price)); err != nil {
+					return utils.Permanent(fmt.Errorf("transaction %s: %w", tx.Hash, err))
+				}
+				if err := sink.Write(row); err != nil {
+					return err
+				}
+			}
+
+			// The log index is unique within the block, it is counted if the node does not return it
+			var logIndex int64
+			for _, receipt := range block.Receipts {
+				for _, log := range receipt.Logs {
+					index, ok := log.LogIndex.integer().(int64)
+					if !ok {
+						index = logIndex
+					}
+					logIndex++
+					hash := log.TransactionHash
+					if hash == "" {
+						hash = receipt.TransactionHash
+					}
+					if err := sink.Write(EvmLogRow{
+						_dlt_extracted_at: extra.ExtractedAt,
+						block_number:      blockNumber,
+						log_index:         index,
+						tx_index:          log.TransactionIndex,
+						tx_hash:           hash,
+						block_hash:        block.Hash,
+						block_timestamp:   block.Timestamp,
+						address:           log.Address,
+						topics:            log.Topics,
+						data:              log.Data,
+						bundle_id:         int64(bundleId),
+					}); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		CompressedSize:   downloadResult.CompressedSize,
+		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
+	}, nil
+}
@@ -0,0 +1,290 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+func TestEvmQuantity(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+		err  bool
+	}{
+		{name: "hex", json: `"0x1a"`, want: "26"},
+		{name: "empty hex", json: `"0x"`, want: "0"},
+		{name: "decimal string", json: `"26"`, want: "26"},
+		{name: "number", json: `26`, want: "26"},
+		{name: "big number", json: `{"type":"BigNumber","hex":"0x1a"}`, want: "26"},
+		{name: "legacy big number", json: `{"_hex":"0x1a","_isBigNumber":true}`, want: "26"},
+		{name: "uint256", json: `"0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"`, want: new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)).String()},
+		{name: "null", json: `null`, want: ""},
+		{name: "empty string", json: `""`, want: ""},
+		{name: "invalid hex", json: `"0xzz"`, err: true},
+		{name: "invalid decimal", json: `"1.5"`, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var q evmQuantity
+			err := json.Unmarshal([]byte(tt.json), &q)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("expected error, got %s", q)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.String() != tt.want {
+				t.Fatalf("got %q, want %q", q.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvmQuantityRanges(t *testing.T) {
+	var large evmQuantity
+	if err := json.Unmarshal([]byte(`"0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"`), &large); err != nil {
+		t.Fatal(err)
+	}
+	if large.integer() != nil || large.numeric() == nil || checkNumeric("value", large) == nil {
+		t.Fatal("expected uint256 max to exceed INTEGER and BIGNUMERIC")
+	}
+
+	// The largest integer of a BIGNUMERIC has 39 digits
+	var limit evmQuantity
+	if err := json.Unmarshal([]byte(`"578960446186580977117854925043439539266"`), &limit); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkNumeric("value", limit); err != nil {
+		t.Fatal(err)
+	}
+	limit.value.Add(limit.value, big.NewInt(1))
+	if checkNumeric("value", limit) == nil {
+		t.Fatal("expected the limit + 1 to exceed BIGNUMERIC")
+	}
+
+	var wei evmQuantity
+	if err := json.Unmarshal([]byte(`"1000000000000000000000000000000"`), &wei); err != nil {
+		t.Fatal(err)
+	}
+	if wei.integer() != nil || fmt.Sprint(wei.numeric()) != "1000000000000000000000000000000" {
+		t.Fatalf("expected 10^30 to fit BIGNUMERIC only, got %v", wei.numeric())
+	}
+}
+
+// convertEvmFixture converts a bundle of the testdata and returns the values of the rows by table and column.
+func convertEvmFixture(t *testing.T, name string) map[string][]map[string]any {
+	t.Helper()
+
+	items, err := os.ReadFile(filepath.Join("testdata", "evm", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := convertItems(t, Evm{}, string(items))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rows
+}
+
+// convertItems converts a bundle with the JSON items and returns the values of the rows by table and column.
+func convertItems(t *testing.T, source DataSource, items string) (map[string][]map[string]any, error) {
+	t.Helper()
+
+	bundle, data := testBundle(t, items)
+	serveGateways(t, data)
+
+	tables := make(map[string]Table)
+	for _, table := range source.Tables() {
+		tables[table.Name] = table
+	}
+
+	rows := make(map[string][]map[string]any)
+	_, err := source.DownloadAndConvertBundle(bundle, ExtraData{ExtractedAt: time.Now()}, RowSinkFunc(func(row DataRow) error {
+		values := row.Values()
+		columns := tables[row.TableName()].ColumnNames()
+		if len(values) != len(columns) {
+			return fmt.Errorf("row of table %s has %d values for %d columns", row.TableName(), len(values), len(columns))
+		}
+		byColumn := make(map[string]any, len(columns))
+		for i, column := range columns {
+			byColumn[column] = values[i]
+		}
+		rows[row.TableName()] = append(rows[row.TableName()], byColumn)
+		return nil
+	}))
+	return rows, err
+}
+
+// assertValues compares the values in their CSV format, nil is an empty string.
+func assertValues(t *testing.T, row map[string]any, want map[string]string) {
+	t.Helper()
+
+	for column, expected := range want {
+		if actual := csvValue(row[column]); actual != expected {
+			t.Errorf("column %s: got %q, want %q", column, actual, expected)
+		}
+	}
+}
+
+func TestEvmHashOnlyTransactions(t *testing.T) {
+	rows := convertEvmFixture(t, "hash_only.json")
+
+	if len(rows["blocks"]) != 1 || len(rows["transactions"]) != 2 || len(rows["logs"]) != 0 {
+		t.Fatalf("unexpected rows: %d blocks, %d transactions, %d logs", len(rows["blocks"]), len(rows["transactions"]), len(rows["logs"]))
+	}
+	assertValues(t, rows["blocks"][0], map[string]string{
+		"block_number":      "100",
+		"timestamp":         "2024-01-01T00:00:00Z",
+		"gas_limit":         "30000000",
+		"gas_used":          "21000",
+		"base_fee_per_gas":  "",
+		"transaction_count": "2",
+	})
+	assertValues(t, rows["transactions"][1], map[string]string{
+		"block_number": "100",
+		"tx_index":     "1",
+		"hash":         "0xt2",
+		"from":         "",
+		"value":        "",
+		"status":       "",
+	})
+}
+
+func TestEvmReceiptsInBlock(t *testing.T) {
+	rows := convertEvmFixture(t, "receipts_in_block.json")
+
+	if len(rows["blocks"]) != 1 || len(rows["transactions"]) != 2 || len(rows["logs"]) != 2 {
+		t.Fatalf("unexpected rows: %d blocks, %d transactions, %d logs", len(rows["blocks"]), len(rows["transactions"]), len(rows["logs"]))
+	}
+	assertValues(t, rows["blocks"][0], map[string]string{
+		"block_number":     "101",
+		"timestamp":        "2024-01-01T00:00:00Z",
+		"gas_limit":        "30000000",
+		"gas_used":         "42000",
+		"base_fee_per_gas": "7",
+	})
+	// The effective gas price of the receipt replaces the gas price
+	assertValues(t, rows["transactions"][0], map[string]string{
+		"tx_index":  "0",
+		"value":     "1000000000000000000",
+		"gas":       "21000",
+		"gas_price": "1000000007",
+		"gas_used":  "21000",
+		"nonce":     "1",
+		"status":    "1",
+		"input":     "0x",
+	})
+	// Contract creation with gasLimit and data instead of gas and input
+	assertValues(t, rows["transactions"][1], map[string]string{
+		"tx_index":         "1",
+		"to":               "",
+		"value":            "0",
+		"gas":              "21000",
+		"gas_price":        "1000000000",
+		"nonce":            "2",
+		"status":           "0",
+		"contract_address": "0xcontract",
+		"input":            "0x60806040",
+	})
+	assertValues(t, rows["logs"][1], map[string]string{
+		"log_index": "1",
+		"tx_index":  "0",
+		"tx_hash":   "0xt1",
+		"address":   "0xtoken",
+		"topics":    "[]",
+		"data":      "0x02",
+	})
+}
+
+func TestEvmReceiptsWrapped(t *testing.T) {
+	rows := convertEvmFixture(t, "receipts_wrapped.json")
+
+	if len(rows["blocks"]) != 1 || len(rows["transactions"]) != 1 || len(rows["logs"]) != 1 {
+		t.Fatalf("unexpected rows: %d blocks, %d transactions, %d logs", len(rows["blocks"]), len(rows["transactions"]), len(rows["logs"]))
+	}
+	assertValues(t, rows["blocks"][0], map[string]string{
+		"block_number": "102",
+		"timestamp":    "2024-01-01T00:00:08Z",
+	})
+	assertValues(t, rows["transactions"][0], map[string]string{
+		"gas_used":  "21000",
+		"gas_price": "1",
+		"status":    "1",
+	})
+	// Missing log index and transaction hash are taken from the position and the receipt
+	assertValues(t, rows["logs"][0], map[string]string{
+		"log_index":       "0",
+		"tx_index":        "",
+		"tx_hash":         "0xt1",
+		"block_timestamp": "2024-01-01T00:00:08Z",
+		"topics":          `["0xa","0xb"]`,
+	})
+	if topics := rows["logs"][0]["topics"]; !reflect.DeepEqual(topics, []any{"0xa", "0xb"}) {
+		t.Fatalf("expected the topics as array, got %#v", topics)
+	}
+}
+
+func TestEvmTopicsRepeated(t *testing.T) {
+	logs := Evm{}.Tables()[2]
+	if logs.Name != "logs" {
+		t.Fatalf("expected the logs table, got %s", logs.Name)
+	}
+	for i, column := range logs.Columns {
+		if column.Name != "topics" {
+			continue
+		}
+		if column.Type != StringColumn || !column.Repeated {
+			t.Fatalf("expected a repeated string column, got %+v", column)
+		}
+		// CSV loads have no arrays
+		if field := logs.BigQueryCSVSchema()[i]; field.Type != bigquery.JSONFieldType || field.Repeated {
+			t.Fatalf("expected a JSON field for CSV loads, got %+v", field)
+		}
+		return
+	}
+	t.Fatal("missing topics column")
+}
+
+func TestEvmRawIdIsDeterministic(t *testing.T) {
+	first := convertEvmFixture(t, "receipts_in_block.json")
+	second := convertEvmFixture(t, "receipts_in_block.json")
+
+	ids := make(map[any]bool)
+	for _, table := range []string{"blocks", "transactions", "logs"} {
+		for i, row := range first[table] {
+			id := row["_dlt_raw_id"]
+			if id != second[table][i]["_dlt_raw_id"] {
+				t.Fatalf("%s row %d: got ids %v and %v", table, i, id, second[table][i]["_dlt_raw_id"])
+			}
+			if ids[id] {
+				t.Fatalf("%s row %d: duplicate id %v", table, i, id)
+			}
+			ids[id] = true
+		}
+	}
+}
+
+func TestEvmValueOutOfRange(t *testing.T) {
+	items := `[{"key":"100","value":{"number":"0x64","timestamp":"0x0","transactions":[` +
+		`{"hash":"0xabc","transactionIndex":"0x0","value":"0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}]}}]`
+	_, err := convertItems(t, Evm{}, items)
+	var permanentErr *utils.PermanentError
+	if !errors.As(err, &permanentErr) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
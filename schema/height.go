@@ -3,12 +3,13 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strconv"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
-	"github.com/google/uuid"
 )
 
 type HeightItem struct {
@@ -16,110 +17,86 @@ type HeightItem struct {
 	Value json.RawMessage `json:"value"`
 }
 
+func (i HeightItem) itemKey() string {
+	return i.Key
+}
+
 type HeightRow struct {
 	_dlt_raw_id       string
-	_dlt_extracted_at string
+	_dlt_extracted_at time.Time
 	height            int64
-	value             string
+	value             json.RawMessage
 	bundle_id         int64
 }
 
-func (t HeightRow) ConvertToCSVLine() []string {
-	return []string{
-		uuid.New().String(),
+func (t HeightRow) TableName() string {
+	return ""
+}
+
+func (t HeightRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.height),
 		t._dlt_extracted_at,
-		strconv.FormatInt(t.height, 10),
+		t.height,
 		t.value,
-		strconv.FormatInt(t.bundle_id, 10),
+		t.bundle_id,
 	}
 }
 
 type Height struct{}
 
-func (t Height) GetBigQuerySchema() bigquery.Schema {
-	return bigquery.Schema{
-		{Name: "_dlt_raw_id", Type: bigquery.StringFieldType},
-		{Name: "_dlt_extracted_at", Type: bigquery.TimestampFieldType},
-		{Name: "height", Type: bigquery.IntegerFieldType},
-		{Name: "value", Type: bigquery.JSONFieldType},
-		{Name: "bundle_id", Type: bigquery.IntegerFieldType},
-	}
-}
-
-func (t Height) GetBigQueryTimePartitioning() *bigquery.TimePartitioning {
-	return &bigquery.TimePartitioning{
-		Field: "_dlt_extracted_at",
-		Type:  bigquery.DayPartitioningType,
-	}
-}
-
-func (t Height) GetBigQueryClustering() *bigquery.Clustering {
-	return &bigquery.Clustering{Fields: []string{"_dlt_extracted_at"}}
-}
-
-func (t Height) GetCSVSchema() []string {
-	return []string{
-		"_dlt_raw_id",
-		"_dlt_extracted_at",
-		"height",
-		"value",
-		"bundle_id",
-	}
-}
-
-func (t Height) GetPostgresCreateTableCommand(name string) string {
-	return fmt.Sprintf(`
-CREATE TABLE IF NOT EXISTS %s (
-    _dlt_raw_id varchar NOT NULL,
-    _dlt_extracted_at timestamp NOT NULL,
-    "height" integer NOT NULL, 
-    "value" varchar, 
-    "bundle_id" integer NOT NULL, 
-    PRIMARY KEY (height)
-    )
-    `, name)
+func (t Height) Tables() []Table {
+	return []Table{{
+		Columns: columns(
+			Column{Name: "height", Type: IntegerColumn, Required: true},
+			Column{Name: "value", Type: JSONColumn},
+		),
+		PrimaryKey: []string{"height"},
+		// The values of the pools differ, so there is no block time to partition by
+		TimePartitioning: &bigquery.TimePartitioning{Field: "_dlt_extracted_at", Type: bigquery.DayPartitioningType},
+		Clustering:       &bigquery.Clustering{Fields: []string{"height"}},
+	}}
 }
 
-func (t Height) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData) (Result, error) {
-	downloadResult, err := downloadBundle(bundle, extra)
-	if err != nil {
-		return Result{}, err
-	}
-
-	var items []HeightItem
-	err = json.Unmarshal(downloadResult.Data.Bytes(), &items)
-	if err != nil {
-		return Result{}, err
-	}
-
+func (t Height) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
 	bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
 
-	columns := make([]DataRow, 0)
-	for _, kyveItem := range items {
-		utils.AwaitEnoughMemory(extra.Name)
-
-		height, err := strconv.ParseUint(kyveItem.Key, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-
-		jsonValue, err := json.Marshal(kyveItem.Value)
-		if err != nil {
-			return Result{}, err
-		}
-		columns = append(columns, HeightRow{
-			_dlt_raw_id:       "",
-			_dlt_extracted_at: extra.ExtractedAt,
-			value:             string(jsonValue),
-			height:            int64(height),
-			bundle_id:         int64(bundleId),
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem HeightItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			height, err := strconv.ParseUint(kyveItem.Key, 10, 64)
+			if err != nil {
+				return utils.Permanent(fmt.Errorf("invalid height %q: %w", kyveItem.Key, err))
+			}
+
+			jsonValue, err := json.Marshal(kyveItem.Value)
+			if err != nil {
+				return err
+			}
+			if err := sink.Write(HeightRow{
+				_dlt_raw_id:       "",
+				_dlt_extracted_at: extra.ExtractedAt,
+				value:             jsonValue,
+				height:            int64(height),
+				bundle_id:         int64(bundleId),
+			}); err != nil {
+				return err
+			}
+			return nil
 		})
-
+		return err
+	})
+	if err != nil {
+		return Result{}, err
 	}
 
 	return Result{
-		Data:             columns,
 		CompressedSize:   downloadResult.CompressedSize,
 		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
 	}, nil
 }
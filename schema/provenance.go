@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+)
+
+type provenanceColumn struct {
+	Column
+	value func(bundle collector.Bundle) any
+}
+
+// provenanceColumns trace every row back to the bundle it was loaded from.
+var provenanceColumns = []provenanceColumn{
+	{Column{Name: "pool_id", Type: IntegerColumn}, func(b collector.Bundle) any { return integer(b.PoolId) }},
+	{Column{Name: "storage_id", Type: StringColumn}, func(b collector.Bundle) any { return optional(b.StorageId) }},
+	{Column{Name: "storage_provider_id", Type: IntegerColumn}, func(b collector.Bundle) any { return integer(b.StorageProviderId) }},
+	{Column{Name: "data_hash", Type: StringColumn}, func(b collector.Bundle) any { return optional(b.DataHash) }},
+	{Column{Name: "uploader", Type: StringColumn}, func(b collector.Bundle) any { return optional(b.Uploader) }},
+	{Column{Name: "finalized_at_height", Type: IntegerColumn}, func(b collector.Bundle) any { return integer(b.FinalizedAt.Height) }},
+	{Column{Name: "finalized_at_time", Type: TimestampColumn}, func(b collector.Bundle) any {
+		if b.FinalizedAt.Timestamp.IsZero() {
+			return nil
+		}
+		return b.FinalizedAt.Timestamp
+	}},
+}
+
+// Provenance extends the rows of a schema with the metadata of their bundle.
+type Provenance struct {
+	DataSource
+}
+
+func WithProvenance(source DataSource) DataSource {
+	return Provenance{DataSource: source}
+}
+
+type provenanceRow struct {
+	DataRow
+	values []any
+}
+
+func (r provenanceRow) Values() []any {
+	return append(r.DataRow.Values(), r.values...)
+}
+
+// Tables appends the provenance columns to all tables, the destinations
+// add them to tables which were created without them.
+func (t Provenance) Tables() []Table {
+	tables := t.DataSource.Tables()
+	for i := range tables {
+		tables[i].Columns = append([]Column{}, tables[i].Columns...)
+		for _, c := range provenanceColumns {
+			tables[i].Columns = append(tables[i].Columns, c.Column)
+		}
+	}
+	return tables
+}
+
+func (t Provenance) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
+	// All rows of a bundle share the same values
+	values := make([]any, len(provenanceColumns))
+	for i, c := range provenanceColumns {
+		values[i] = c.value(bundle)
+	}
+	return t.DataSource.DownloadAndConvertBundle(bundle, extra, RowSinkFunc(func(row DataRow) error {
+		return sink.Write(provenanceRow{DataRow: row, values: values})
+	}))
+}
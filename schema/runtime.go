@@ -0,0 +1,23 @@
+package schema
+
+// Auto selects the schema based on the runtime of the pool in dlt sources add,
+// the config holds the selected schema.
+const Auto = "auto"
+
+// Names lists all available schemas.
+var Names = []string{"base", "height", "tendermint_preprocessed", "evm", "cosmos_txs", "tendermint_events"}
+
+// runtimeSchemas maps the KYVE runtimes to the schema which fits their data items best.
+var runtimeSchemas = map[string]string{
+	"@kyvejs/tendermint":       "tendermint_preprocessed",
+	"@kyvejs/tendermint-bsync": "height",
+	"@kyvejs/evm":              "evm",
+}
+
+// ForRuntime returns the recommended schema of a pool runtime, base supports all runtimes.
+func ForRuntime(runtime string) string {
+	if name, ok := runtimeSchemas[runtime]; ok {
+		return name
+	}
+	return "base"
+}
@@ -1,61 +1,222 @@
 package schema
 
 import (
-	"bytes"
-	"compress/gzip"
-	"crypto/sha256"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
 )
 
-func downloadBundle(bundle collector.Bundle, extra ExtraData) (DownloadResult, error) {
+const defaultGatewayTimeout = 5 * time.Minute
 
-	baseUrl := ""
-	switch bundle.StorageProviderId {
-	case "1":
-		baseUrl = "https://bundles.services.kyve.network"
-	case "2":
-		baseUrl = "https://bundles.services.kyve.network"
-	case "3":
-		baseUrl = "https://storage.kyve.network"
-	case "4":
-		baseUrl = "https://bundles.services.kyve.network"
+var (
+	logger = utils.DltLogger("schema")
+)
+
+type Gateway struct {
+	Url     string
+	Timeout time.Duration
+}
+
+type StorageProvider struct {
+	Id   string
+	Name string
+	// Gateways are tried in the given order
+	Gateways []Gateway
+}
+
+var (
+	storageProvidersMutex sync.RWMutex
+	storageProviders      = defaultStorageProviders()
+)
+
+// corruptGateways holds the gateways per storage ID which served data with a wrong checksum.
+// They are skipped when the bundle is downloaded again, so that a single corrupted mirror
+// doesn't block the bundle.
+var corruptGateways = struct {
+	sync.Mutex
+	byStorageId map[string]map[string]bool
+}{byStorageId: make(map[string]map[string]bool)}
+
+func markGatewayCorrupt(storageId string, gateway Gateway) {
+	corruptGateways.Lock()
+	defer corruptGateways.Unlock()
+
+	if corruptGateways.byStorageId[storageId] == nil {
+		corruptGateways.byStorageId[storageId] = make(map[string]bool)
 	}
+	corruptGateways.byStorageId[storageId][gateway.Url] = true
+}
 
-	// Download bundle
-	resp, err := http.Get(fmt.Sprintf("%s/%s", baseUrl, bundle.StorageId))
-	if err != nil {
-		return DownloadResult{}, err
+func forgetCorruptGateways(storageId string) {
+	corruptGateways.Lock()
+	defer corruptGateways.Unlock()
+
+	delete(corruptGateways.byStorageId, storageId)
+}
+
+// usableGateways returns the gateways of the provider which didn't serve corrupted data for the
+// storage ID. If all of them did, they are all tried again.
+func usableGateways(provider StorageProvider, storageId string) []Gateway {
+	corruptGateways.Lock()
+	defer corruptGateways.Unlock()
+
+	corrupt := corruptGateways.byStorageId[storageId]
+	gateways := make([]Gateway, 0, len(provider.Gateways))
+	for _, gateway := range provider.Gateways {
+		if !corrupt[gateway.Url] {
+			gateways = append(gateways, gateway)
+		}
+	}
+
+	if len(gateways) == 0 {
+		logger.Warn().Str("storage_id", storageId).Msg("all gateways served corrupted data, trying them again")
+		delete(corruptGateways.byStorageId, storageId)
+		return provider.Gateways
+	}
+	return gateways
+}
+
+func defaultStorageProviders() map[string]StorageProvider {
+	return map[string]StorageProvider{
+		"1": {Id: "1", Name: "Arweave", Gateways: []Gateway{
+			{Url: "https://bundles.services.kyve.network", Timeout: defaultGatewayTimeout},
+			{Url: "https://arweave.net", Timeout: defaultGatewayTimeout},
+		}},
+		"2": {Id: "2", Name: "Bundlr", Gateways: []Gateway{
+			{Url: "https://bundles.services.kyve.network", Timeout: defaultGatewayTimeout},
+			{Url: "https://arweave.net", Timeout: defaultGatewayTimeout},
+		}},
+		"3": {Id: "3", Name: "KYVE Storage", Gateways: []Gateway{
+			{Url: "https://storage.kyve.network", Timeout: defaultGatewayTimeout},
+		}},
+		"4": {Id: "4", Name: "Turbo", Gateways: []Gateway{
+			{Url: "https://bundles.services.kyve.network", Timeout: defaultGatewayTimeout},
+			{Url: "https://arweave.net", Timeout: defaultGatewayTimeout},
+		}},
+	}
+}
+
+// SetStorageProviders adds the storage providers of the config to the registry.
+// A provider with an existing ID replaces the default gateways.
+func SetStorageProviders(providers []utils.StorageProvider) error {
+	registry := defaultStorageProviders()
+
+	for _, p := range providers {
+		id := strconv.Itoa(p.Id)
+		if len(p.Gateways) == 0 {
+			return fmt.Errorf("storage provider %s has no gateways", id)
+		}
+
+		provider := StorageProvider{Id: id, Name: p.Name}
+		for _, g := range p.Gateways {
+			if g.Url == "" {
+				return fmt.Errorf("storage provider %s has a gateway without url", id)
+			}
+
+			timeout := defaultGatewayTimeout
+			if g.Timeout > 0 {
+				timeout = time.Duration(g.Timeout) * time.Second
+			}
+			provider.Gateways = append(provider.Gateways, Gateway{
+				Url:     strings.TrimSuffix(g.Url, "/"),
+				Timeout: timeout,
+			})
+		}
+		registry[id] = provider
+	}
+
+	storageProvidersMutex.Lock()
+	storageProviders = registry
+	storageProvidersMutex.Unlock()
+
+	return nil
+}
+
+func GetStorageProvider(id string) (StorageProvider, error) {
+	storageProvidersMutex.RLock()
+	defer storageProvidersMutex.RUnlock()
+
+	provider, ok := storageProviders[id]
+	if !ok {
+		return StorageProvider{}, fmt.Errorf("unknown storage provider id %s, add it to storage_providers in the config", id)
 	}
-	defer resp.Body.Close()
+	return provider, nil
+}
 
-	responseBuffer := new(bytes.Buffer)
-	responseBuffer.ReadFrom(resp.Body)
-	rawData := responseBuffer.Bytes()
+// openFromGateways opens the object at the first gateway which responds successfully,
+// gateways which served corrupted data for the object before are skipped.
+func openFromGateways(provider StorageProvider, storageId string) (io.ReadCloser, Gateway, error) {
+	var errs []error
+	for _, gateway := range usableGateways(provider, storageId) {
+		body, err := openFromGateway(gateway, storageId)
+		if err == nil {
+			return body, gateway, nil
+		}
 
-	// Verify checksum
-	utils.AwaitEnoughMemory(extra.Name)
-	sha256hash := sha256.Sum256(rawData)
-	if fmt.Sprintf("%x", sha256hash) != bundle.DataHash {
-		return DownloadResult{}, errors.New("checksum does not match")
+		logger.Debug().Str("gateway", gateway.Url).Str("storage_id", storageId).Str("err", err.Error()).Msg("gateway failed, trying next")
+		errs = append(errs, fmt.Errorf("%s: %w", gateway.Url, err))
 	}
+	return nil, Gateway{}, fmt.Errorf("all gateways of storage provider %s failed: %w", provider.Id, errors.Join(errs...))
+}
+
+// openFromGateway returns the response body. The timeout of the gateway limits the time until
+// the response headers arrive and then the time between two reads of the body, so that a slow
+// conversion of the rows doesn't count as a stalled download.
+func openFromGateway(gateway Gateway, storageId string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	timer := time.AfterFunc(gateway.Timeout, cancel)
 
-	// uncompress gzip
-	utils.AwaitEnoughMemory(extra.Name)
-	reader, err := gzip.NewReader(responseBuffer)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", gateway.Url, storageId), nil)
 	if err != nil {
-		return DownloadResult{}, err
+		cancel()
+		return nil, err
 	}
-	bundleBuffer := new(bytes.Buffer)
-	bundleBuffer.ReadFrom(reader)
+	resp, err := http.DefaultClient.Do(req)
+	timer.Stop()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("invalid status code: %d", resp.StatusCode)
+	}
+	return &stallReader{body: resp.Body, length: resp.ContentLength, timeout: gateway.Timeout, timer: timer, cancel: cancel}, nil
+}
+
+// stallReader cancels the request if a single read of the body takes longer than the timeout.
+type stallReader struct {
+	body    io.ReadCloser
+	length  int64
+	timeout time.Duration
+	timer   *time.Timer
+	cancel  context.CancelFunc
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	defer r.timer.Stop()
+
+	return r.body.Read(p)
+}
+
+// Size returns the content length of the response, -1 if it is unknown.
+func (r *stallReader) Size() int64 {
+	return r.length
+}
 
-	return DownloadResult{
-		Data:             bundleBuffer,
-		CompressedSize:   int64(len(rawData)),
-		UncompressedSize: int64(bundleBuffer.Len()),
-	}, nil
+func (r *stallReader) Close() error {
+	r.timer.Stop()
+	defer r.cancel()
+	return r.body.Close()
 }
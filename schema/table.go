@@ -0,0 +1,146 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/uuid"
+)
+
+type ColumnType string
+
+const (
+	StringColumn     ColumnType = "STRING"
+	IntegerColumn    ColumnType = "INTEGER"
+	BigNumericColumn ColumnType = "BIGNUMERIC"
+	TimestampColumn  ColumnType = "TIMESTAMP"
+	JSONColumn       ColumnType = "JSON"
+	BytesColumn      ColumnType = "BYTES"
+)
+
+// postgresTypes maps the column types to Postgres, JSON is
+// stored as varchar like in the tables created so far.
+var postgresTypes = map[ColumnType]string{
+	StringColumn:     "varchar",
+	IntegerColumn:    "bigint",
+	BigNumericColumn: "numeric",
+	TimestampColumn:  "timestamp",
+	JSONColumn:       "varchar",
+	BytesColumn:      "bytea",
+}
+
+type Column struct {
+	Name string
+	Type ColumnType
+	// Required columns are NOT NULL in Postgres, BigQuery columns are always nullable
+	Required bool
+	// Repeated columns are arrays of the type
+	Repeated bool
+}
+
+func (c Column) postgresType() string {
+	if c.Repeated {
+		return postgresTypes[c.Type] + "[]"
+	}
+	return postgresTypes[c.Type]
+}
+
+// Table is one output table of a schema.
+type Table struct {
+	// Name is appended to the table name of the destination, empty for the main table
+	Name       string
+	Columns    []Column
+	PrimaryKey []string
+
+	TimePartitioning  *bigquery.TimePartitioning
+	RangePartitioning *bigquery.RangePartitioning
+	Clustering        *bigquery.Clustering
+}
+
+// FullName returns the name of the table in a destination with the given base name.
+func (t Table) FullName(base string) string {
+	if t.Name == "" {
+		return base
+	}
+	return base + "_" + t.Name
+}
+
+func (t Table) ColumnNames() []string {
+	names := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+func (t Table) BigQuerySchema() bigquery.Schema {
+	fields := make(bigquery.Schema, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		fields = append(fields, &bigquery.FieldSchema{Name: c.Name, Type: bigquery.FieldType(c.Type), Repeated: c.Repeated})
+	}
+	return fields
+}
+
+// BigQueryCSVSchema returns the schema of CSV loads. CSV has no arrays, repeated columns are
+// loaded as JSON arrays instead.
+func (t Table) BigQueryCSVSchema() bigquery.Schema {
+	fields := t.BigQuerySchema()
+	for _, field := range fields {
+		if field.Repeated {
+			field.Type, field.Repeated = bigquery.JSONFieldType, false
+		}
+	}
+	return fields
+}
+
+// PostgresCreateTable returns the statements which create the table and add
+// columns which are missing in tables created by an earlier version.
+func (t Table) PostgresCreateTable(name string) string {
+	definitions := make([]string, 0, len(t.Columns)+1)
+	for _, c := range t.Columns {
+		definition := fmt.Sprintf(`"%s" %s`, c.Name, c.postgresType())
+		if c.Required {
+			definition += " NOT NULL"
+		}
+		definitions = append(definitions, definition)
+	}
+	if len(t.PrimaryKey) > 0 {
+		definitions = append(definitions, fmt.Sprintf(`PRIMARY KEY ("%s")`, strings.Join(t.PrimaryKey, `", "`)))
+	}
+
+	statements := []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n    %s\n)", name, strings.Join(definitions, ",\n    "))}
+	for _, c := range t.Columns {
+		statements = append(statements, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS "%s" %s`, name, c.Name, c.postgresType()))
+	}
+	return strings.Join(statements, ";\n")
+}
+
+// dltColumns are the first columns of every table.
+var dltColumns = []Column{
+	{Name: "_dlt_raw_id", Type: StringColumn, Required: true},
+	{Name: "_dlt_extracted_at", Type: TimestampColumn, Required: true},
+}
+
+// rawIdNamespace is the UUID namespace of the _dlt_raw_id values.
+var rawIdNamespace = uuid.MustParse("5c1f2b7e-3d4a-4e8b-9f60-2a7d8c1e4b93")
+
+// rawId returns the _dlt_raw_id of a row, a UUIDv5 of the table name and the values of the
+// primary key. Rows which are loaded again keep their id, so duplicates can be removed.
+func rawId(table string, key ...any) string {
+	parts := make([]string, 0, len(key)+1)
+	parts = append(parts, table)
+	for _, k := range key {
+		parts = append(parts, fmt.Sprint(k))
+	}
+	return uuid.NewSHA1(rawIdNamespace, []byte(strings.Join(parts, "\x00"))).String()
+}
+
+var bundleIdColumn = Column{Name: "bundle_id", Type: IntegerColumn, Required: true}
+
+// columns returns the columns of a table framed by the dlt columns and the bundle id.
+func columns(columns ...Column) []Column {
+	result := append([]Column{}, dltColumns...)
+	result = append(result, columns...)
+	return append(result, bundleIdColumn)
+}
@@ -0,0 +1,218 @@
+package schema
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+type tendermintEvent struct {
+	Type       string `json:"type"`
+	Attributes []struct {
+		Key   *string `json:"key"`
+		Value *string `json:"value"`
+	} `json:"attributes"`
+}
+
+type TendermintEventsItem struct {
+	Key   string `json:"key"`
+	Value struct {
+		Block        tendermintBlockTime `json:"block"`
+		BlockResults struct {
+			TxsResults []struct {
+				Events []tendermintEvent `json:"events"`
+			} `json:"txs_results"`
+			BeginBlockEvents    []tendermintEvent `json:"begin_block_events"`
+			EndBlockEvents      []tendermintEvent `json:"end_block_events"`
+			FinalizeBlockEvents []tendermintEvent `json:"finalize_block_events"`
+		} `json:"block_results"`
+	} `json:"value"`
+}
+
+func (i TendermintEventsItem) itemKey() string {
+	return i.Key
+}
+
+type TendermintEventRow struct {
+	_dlt_raw_id       string
+	_dlt_extracted_at time.Time
+	height            int64
+	block_time        time.Time
+	source            string
+	tx_index          *int64
+	event_index       int64
+	event_type        string
+	attr_index        int64
+	attr_key          *string
+	attr_value        *string
+	bundle_id         int64
+}
+
+func (t TendermintEventRow) TableName() string {
+	return ""
+}
+
+func (t TendermintEventRow) Values() []any {
+	var txIndex, attrKey, attrValue any
+	if t.tx_index != nil {
+		txIndex = *t.tx_index
+	}
+	if t.attr_key != nil {
+		attrKey = *t.attr_key
+	}
+	if t.attr_value != nil {
+		attrValue = *t.attr_value
+	}
+	return []any{
+		rawId(t.TableName(), t.height, t.event_index, t.attr_index),
+		t._dlt_extracted_at,
+		t.height,
+		optionalTime(t.block_time),
+		t.source,
+		txIndex,
+		t.event_index,
+		t.event_type,
+		t.attr_index,
+		attrKey,
+		attrValue,
+		t.bundle_id,
+	}
+}
+
+// TendermintEvents flattens the events of Tendermint blocks. Chains before Tendermint 0.35
+// encode the keys and values of the attributes with base64.
+type TendermintEvents struct {
+	// Base64Attributes decodes the keys and values of the attributes
+	Base64Attributes bool
+	// Base64AttributesBelowHeight limits the decoding to blocks below the height, e.g. the upgrade
+	// of the chain to Tendermint 0.35. Zero decodes the attributes of all blocks.
+	Base64AttributesBelowHeight int64
+}
+
+// base64Attributes reports whether the attributes of the block are base64 encoded.
+func (t TendermintEvents) base64Attributes(height int64) bool {
+	if !t.Base64Attributes {
+		return false
+	}
+	return t.Base64AttributesBelowHeight == 0 || height < t.Base64AttributesBelowHeight
+}
+
+func (t TendermintEvents) Tables() []Table {
+	return []Table{{
+		Columns: columns(
+			Column{Name: "height", Type: IntegerColumn, Required: true},
+			Column{Name: "block_time", Type: TimestampColumn},
+			Column{Name: "source", Type: StringColumn, Required: true},
+			Column{Name: "tx_index", Type: IntegerColumn},
+			Column{Name: "event_index", Type: IntegerColumn, Required: true},
+			Column{Name: "event_type", Type: StringColumn, Required: true},
+			Column{Name: "attr_index", Type: IntegerColumn, Required: true},
+			Column{Name: "attr_key", Type: StringColumn},
+			Column{Name: "attr_value", Type: StringColumn},
+		),
+		PrimaryKey:       []string{"height", "event_index", "attr_index"},
+		TimePartitioning: &bigquery.TimePartitioning{Field: "block_time", Type: bigquery.DayPartitioningType},
+		Clustering:       &bigquery.Clustering{Fields: []string{"event_type", "attr_key", "height"}},
+	}}
+}
+
+func (t TendermintEvents) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
+	bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
+
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem TendermintEventsItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			height, err := strconv.ParseInt(kyveItem.Key, 10, 64)
+			if err != nil {
+				return utils.Permanent(fmt.Errorf("invalid height %q: %w", kyveItem.Key, err))
+			}
+
+			// Events are numbered in the order in which they are emitted in the block
+			type sourceEvents struct {
+				source  string
+				txIndex *int64
+				events  []tendermintEvent
+			}
+			results := kyveItem.Value.BlockResults
+			sources := []sourceEvents{{source: "begin_block_event", events: results.BeginBlockEvents}}
+			for index, txResult := range results.TxsResults {
+				txIndex := int64(index)
+				sources = append(sources, sourceEvents{source: "tx_result", txIndex: &txIndex, events: txResult.Events})
+			}
+			sources = append(sources,
+				sourceEvents{source: "end_block_event", events: results.EndBlockEvents},
+				sourceEvents{source: "finalize_block_event", events: results.FinalizeBlockEvents},
+			)
+
+			encoded := t.base64Attributes(height)
+			blockTime := kyveItem.Value.Block.time()
+
+			var eventIndex int64
+			for _, s := range sources {
+				for _, event := range s.events {
+					row := TendermintEventRow{
+						_dlt_extracted_at: extra.ExtractedAt,
+						height:            height,
+						block_time:        blockTime,
+						source:            s.source,
+						tx_index:          s.txIndex,
+						event_index:       eventIndex,
+						event_type:        event.Type,
+						bundle_id:         int64(bundleId),
+					}
+					eventIndex++
+
+					// Events without attributes are kept as a single row without key and value
+					if len(event.Attributes) == 0 {
+						if err := sink.Write(row); err != nil {
+							return err
+						}
+						continue
+					}
+					for attrIndex, attribute := range event.Attributes {
+						row.attr_index = int64(attrIndex)
+						row.attr_key = decodeAttribute(attribute.Key, encoded)
+						row.attr_value = decodeAttribute(attribute.Value, encoded)
+						if err := sink.Write(row); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		CompressedSize:   downloadResult.CompressedSize,
+		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
+	}, nil
+}
+
+func decodeAttribute(value *string, encoded bool) *string {
+	if value == nil || !encoded {
+		return value
+	}
+	decoded, err := base64.StdEncoding.DecodeString(*value)
+	if err != nil {
+		// Keep values which are not encoded although the keys are
+		return value
+	}
+	result := string(decoded)
+	return &result
+}
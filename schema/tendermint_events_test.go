@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+// tendermintEventsItem returns an item with one transfer event, base64 encoded like chains before Tendermint 0.35.
+func tendermintEventsItem(height int64, encoded bool) string {
+	attribute := func(value string) string {
+		if encoded {
+			value = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+		return `"` + value + `"`
+	}
+	return fmt.Sprintf(`{"key":"%d","value":{"block_results":{"txs_results":[{"events":[{"type":"transfer","attributes":[`+
+		`{"key":%s,"value":%s},{"key":%s,"value":%s}]}]}],"end_block_events":[{"type":"empty","attributes":[]}]}}}`,
+		height, attribute("recipient"), attribute("cosmos1abc"), attribute("amount"), attribute("100uatom"))
+}
+
+func TestTendermintEventsAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		source TendermintEvents
+		items  []string
+		// Keys and values of the transfer attributes per item
+		want [][]string
+	}{
+		{
+			name:   "plain",
+			source: TendermintEvents{},
+			items:  []string{tendermintEventsItem(100, false)},
+			want:   [][]string{{"recipient", "cosmos1abc", "amount", "100uatom"}},
+		},
+		{
+			// Plain keys which are valid base64 are not decoded by accident
+			name:   "plain base64 alphabet",
+			source: TendermintEvents{},
+			items:  []string{strings.ReplaceAll(tendermintEventsItem(100, false), "recipient", "receiver")},
+			want:   [][]string{{"receiver", "cosmos1abc", "amount", "100uatom"}},
+		},
+		{
+			name:   "base64",
+			source: TendermintEvents{Base64Attributes: true},
+			items:  []string{tendermintEventsItem(100, true)},
+			want:   [][]string{{"recipient", "cosmos1abc", "amount", "100uatom"}},
+		},
+		{
+			name:   "base64 disabled",
+			source: TendermintEvents{},
+			items:  []string{tendermintEventsItem(100, true)},
+			want:   [][]string{{"cmVjaXBpZW50", "Y29zbW9zMWFiYw==", "YW1vdW50", "MTAwdWF0b20="}},
+		},
+		{
+			name:   "base64 below upgrade height",
+			source: TendermintEvents{Base64Attributes: true, Base64AttributesBelowHeight: 101},
+			items:  []string{tendermintEventsItem(100, true), tendermintEventsItem(101, false)},
+			want: [][]string{
+				{"recipient", "cosmos1abc", "amount", "100uatom"},
+				{"recipient", "cosmos1abc", "amount", "100uatom"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := convertItems(t, tt.source, "["+strings.Join(tt.items, ",")+"]")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Two transfer attributes and the empty event per item
+			if len(rows[""]) != 3*len(tt.items) {
+				t.Fatalf("got %d rows, want %d", len(rows[""]), 3*len(tt.items))
+			}
+			for i, want := range tt.want {
+				transfer := rows[""][3*i : 3*i+2]
+				got := []string{
+					csvValue(transfer[0]["attr_key"]), csvValue(transfer[0]["attr_value"]),
+					csvValue(transfer[1]["attr_key"]), csvValue(transfer[1]["attr_value"]),
+				}
+				if strings.Join(got, " ") != strings.Join(want, " ") {
+					t.Fatalf("item %d: got %q, want %q", i, got, want)
+				}
+				assertValues(t, rows[""][3*i+2], map[string]string{
+					"source":     "end_block_event",
+					"event_type": "empty",
+					"attr_key":   "",
+				})
+			}
+		})
+	}
+}
+
+func TestTendermintEventsInvalidHeight(t *testing.T) {
+	_, err := convertItems(t, TendermintEvents{}, `[{"key":"latest","value":{"block_results":{}}}]`)
+	var permanentErr *utils.PermanentError
+	if !errors.As(err, &permanentErr) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
+
+func TestTendermintEventsBlockTime(t *testing.T) {
+	items := `[{"key":"100","value":{"block":{"block":{"header":{"time":"2024-01-01T12:00:00.5Z"}}},` +
+		`"block_results":{"end_block_events":[{"type":"empty","attributes":[]}]}}},` +
+		`{"key":"101","value":{"block_results":{"end_block_events":[{"type":"empty","attributes":[]}]}}}]`
+	rows, err := convertItems(t, TendermintEvents{}, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows[""]) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows[""]))
+	}
+	assertValues(t, rows[""][0], map[string]string{"height": "100", "block_time": "2024-01-01T12:00:00.5Z"})
+	// Items without a block have no block time
+	assertValues(t, rows[""][1], map[string]string{"height": "101", "block_time": ""})
+}
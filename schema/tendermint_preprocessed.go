@@ -3,12 +3,13 @@ package schema
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strconv"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
 	"github.com/KYVENetwork/KYVE-DLT/utils"
-	"github.com/google/uuid"
 )
 
 type TendermintPreProcessedItem struct {
@@ -27,6 +28,10 @@ type TendermintPreProcessedItem struct {
 	} `json:"value"`
 }
 
+func (i TendermintPreProcessedItem) itemKey() string {
+	return i.Key
+}
+
 type TendermintPreProcessedValue struct {
 	Block        json.RawMessage                    `json:"block"`
 	BlockResults TendermintPreProcessedBlockResults `json:"block_results"`
@@ -42,175 +47,186 @@ type TendermintPreProcessedBlockResults struct {
 	ConsensusParamUpdates struct{}          `json:"consensus_param_updates"`
 }
 
+// tendermintBlockTime decodes the header time of a block, which is nested in older runtime versions.
+type tendermintBlockTime struct {
+	Block  *tendermintBlockTime `json:"block"`
+	Header struct {
+		Time time.Time `json:"time"`
+	} `json:"header"`
+}
+
+func (b *tendermintBlockTime) time() time.Time {
+	for b.Block != nil {
+		b = b.Block
+	}
+	return b.Header.Time
+}
+
 type TendermintPreProcessedRow struct {
 	_dlt_raw_id       string
-	_dlt_extracted_at string
+	_dlt_extracted_at time.Time
+	block_time        time.Time
 	item_type         string
-	value             string
-	height            string
+	value             json.RawMessage
+	height            int64
 	array_index       int64
 	bundle_id         int64
 }
 
-func (t TendermintPreProcessedRow) ConvertToCSVLine() []string {
-	return []string{
-		uuid.New().String(),
+func (t TendermintPreProcessedRow) TableName() string {
+	return ""
+}
+
+func (t TendermintPreProcessedRow) Values() []any {
+	return []any{
+		rawId(t.TableName(), t.height, t.item_type, t.array_index),
 		t._dlt_extracted_at,
 		t.height,
+		optionalTime(t.block_time),
 		t.item_type,
-		strconv.FormatInt(t.array_index, 10),
+		t.array_index,
 		t.value,
-		strconv.FormatInt(t.bundle_id, 10),
+		t.bundle_id,
 	}
 }
 
 type TendermintPreProcessed struct{}
 
-func (t TendermintPreProcessed) GetBigQuerySchema() bigquery.Schema {
-	return bigquery.Schema{
-		{Name: "_dlt_raw_id", Type: bigquery.StringFieldType},
-		{Name: "_dlt_extracted_at", Type: bigquery.TimestampFieldType},
-		{Name: "height", Type: bigquery.IntegerFieldType},
-		{Name: "type", Type: bigquery.StringFieldType},
-		{Name: "array_index", Type: bigquery.IntegerFieldType},
-		{Name: "value", Type: bigquery.JSONFieldType},
-		{Name: "bundle_id", Type: bigquery.IntegerFieldType},
-	}
+func (t TendermintPreProcessed) Tables() []Table {
+	return []Table{{
+		Columns: columns(
+			Column{Name: "height", Type: IntegerColumn, Required: true},
+			Column{Name: "block_time", Type: TimestampColumn},
+			Column{Name: "type", Type: StringColumn, Required: true},
+			Column{Name: "array_index", Type: IntegerColumn, Required: true},
+			Column{Name: "value", Type: JSONColumn},
+		),
+		PrimaryKey:       []string{"height", "type", "array_index"},
+		TimePartitioning: &bigquery.TimePartitioning{Field: "block_time", Type: bigquery.DayPartitioningType},
+		Clustering:       &bigquery.Clustering{Fields: []string{"type", "height"}},
+	}}
 }
 
-func (t TendermintPreProcessed) GetBigQueryTimePartitioning() *bigquery.TimePartitioning {
-	return &bigquery.TimePartitioning{
-		Field: "_dlt_extracted_at",
-		Type:  bigquery.DayPartitioningType,
-	}
-}
-
-func (t TendermintPreProcessed) GetBigQueryClustering() *bigquery.Clustering {
-	return &bigquery.Clustering{Fields: []string{"_dlt_extracted_at"}}
-}
-
-func (t TendermintPreProcessed) GetCSVSchema() []string {
-	return []string{
-		"_dlt_raw_id",
-		"_dlt_extracted_at",
-		"height",
-		"type",
-		"array_index",
-		"value",
-		"bundle_id",
-	}
-}
-
-func (t TendermintPreProcessed) GetPostgresCreateTableCommand(name string) string {
-	return fmt.Sprintf(`
-CREATE TABLE IF NOT EXISTS %s (
-    _dlt_raw_id varchar NOT NULL,
-    _dlt_extracted_at timestamp NOT NULL,
-    "height" integer NOT NULL, 
-    "type" varchar, 
-    "array_index" integer NOT NULL, 
-    "value" varchar, 
-    "bundle_id" integer NOT NULL, 
-    PRIMARY KEY (height, type, array_index)
-    )
-    `, name)
-}
-
-func (t TendermintPreProcessed) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData) (Result, error) {
-	downloadResult, err := downloadBundle(bundle, extra)
-	if err != nil {
-		return Result{}, err
-	}
-
-	var items []TendermintPreProcessedItem
-	err = json.Unmarshal(downloadResult.Data.Bytes(), &items)
-	if err != nil {
-		return Result{}, err
-	}
-
-	columns := make([]DataRow, 0)
-	for _, kyveItem := range items {
-		utils.AwaitEnoughMemory(extra.Name)
-
-		prunedBlockResults := TendermintPreProcessedBlockResults{
-			Height:                kyveItem.Value.BlockResults.Height,
-			TxsResults:            nil,
-			BeginBlockEvents:      nil,
-			EndBlockEvents:        nil,
-			FinalizeBlockEvents:   nil,
-			ValidatorUpdates:      kyveItem.Value.BlockResults.ValidatorUpdates,
-			ConsensusParamUpdates: kyveItem.Value.BlockResults.ConsensusParamUpdates,
-		}
-
-		prunedItem := TendermintPreProcessedValue{
-			Block:        kyveItem.Value.Block,
-			BlockResults: prunedBlockResults,
-		}
-
-		prunedJson, err := json.Marshal(prunedItem)
-		if err != nil {
-			return Result{}, err
-		}
-
-		bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
-		columns = append(columns, TendermintPreProcessedRow{
-			_dlt_raw_id:       "",
-			_dlt_extracted_at: extra.ExtractedAt,
-			item_type:         "block",
-			value:             string(prunedJson),
-			height:            kyveItem.Key,
-			array_index:       0,
-			bundle_id:         int64(bundleId),
-		})
-		for index, beginBlockItem := range kyveItem.Value.BlockResults.BeginBlockEvents {
-			columns = append(columns, TendermintPreProcessedRow{
-				_dlt_raw_id:       "",
-				_dlt_extracted_at: extra.ExtractedAt,
-				item_type:         "begin_block_event",
-				value:             string(beginBlockItem),
-				height:            kyveItem.Key,
-				array_index:       int64(index),
-				bundle_id:         int64(bundleId),
-			})
-		}
-		for index, txResult := range kyveItem.Value.BlockResults.TxsResults {
-			columns = append(columns, TendermintPreProcessedRow{
-				_dlt_raw_id:       "",
-				_dlt_extracted_at: extra.ExtractedAt,
-				item_type:         "tx_result",
-				value:             string(txResult),
-				height:            kyveItem.Key,
-				array_index:       int64(index),
-				bundle_id:         int64(bundleId),
-			})
-		}
-		for index, endBlockEvents := range kyveItem.Value.BlockResults.EndBlockEvents {
-			columns = append(columns, TendermintPreProcessedRow{
-				_dlt_raw_id:       "",
-				_dlt_extracted_at: extra.ExtractedAt,
-				item_type:         "end_block_event",
-				value:             string(endBlockEvents),
-				height:            kyveItem.Key,
-				array_index:       int64(index),
-				bundle_id:         int64(bundleId),
-			})
-		}
-		for index, finalizeBlockEvents := range kyveItem.Value.BlockResults.FinalizeBlockEvents {
-			columns = append(columns, TendermintPreProcessedRow{
+func (t TendermintPreProcessed) DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error) {
+	var items ItemStats
+	downloadResult, err := downloadBundle(bundle, extra, func(data io.Reader) (err error) {
+		items, err = decodeItems(data, extra, func(kyveItem TendermintPreProcessedItem) error {
+			if !extra.KeyRange.Contains(kyveItem.Key) {
+				return nil
+			}
+
+			height, err := strconv.ParseInt(kyveItem.Key, 10, 64)
+			if err != nil {
+				return utils.Permanent(fmt.Errorf("invalid height %q: %w", kyveItem.Key, err))
+			}
+
+			var block tendermintBlockTime
+			if len(kyveItem.Value.Block) > 0 {
+				if err := json.Unmarshal(kyveItem.Value.Block, &block); err != nil {
+					return fmt.Errorf("invalid block at height %d: %w", height, err)
+				}
+			}
+
+			prunedBlockResults := TendermintPreProcessedBlockResults{
+				Height:                kyveItem.Value.BlockResults.Height,
+				TxsResults:            nil,
+				BeginBlockEvents:      nil,
+				EndBlockEvents:        nil,
+				FinalizeBlockEvents:   nil,
+				ValidatorUpdates:      kyveItem.Value.BlockResults.ValidatorUpdates,
+				ConsensusParamUpdates: kyveItem.Value.BlockResults.ConsensusParamUpdates,
+			}
+
+			prunedItem := TendermintPreProcessedValue{
+				Block:        kyveItem.Value.Block,
+				BlockResults: prunedBlockResults,
+			}
+
+			prunedJson, err := json.Marshal(prunedItem)
+			if err != nil {
+				return err
+			}
+
+			bundleId, _ := strconv.ParseUint(bundle.Id, 10, 64)
+			if err := sink.Write(TendermintPreProcessedRow{
 				_dlt_raw_id:       "",
 				_dlt_extracted_at: extra.ExtractedAt,
-				item_type:         "finalize_block_event",
-				value:             string(finalizeBlockEvents),
-				height:            kyveItem.Key,
-				array_index:       int64(index),
+				block_time:        block.time(),
+				item_type:         "block",
+				value:             prunedJson,
+				height:            height,
+				array_index:       0,
 				bundle_id:         int64(bundleId),
-			})
-		}
+			}); err != nil {
+				return err
+			}
+			for index, beginBlockItem := range kyveItem.Value.BlockResults.BeginBlockEvents {
+				if err := sink.Write(TendermintPreProcessedRow{
+					_dlt_raw_id:       "",
+					_dlt_extracted_at: extra.ExtractedAt,
+					block_time:        block.time(),
+					item_type:         "begin_block_event",
+					value:             beginBlockItem,
+					height:            height,
+					array_index:       int64(index),
+					bundle_id:         int64(bundleId),
+				}); err != nil {
+					return err
+				}
+			}
+			for index, txResult := range kyveItem.Value.BlockResults.TxsResults {
+				if err := sink.Write(TendermintPreProcessedRow{
+					_dlt_raw_id:       "",
+					_dlt_extracted_at: extra.ExtractedAt,
+					block_time:        block.time(),
+					item_type:         "tx_result",
+					value:             txResult,
+					height:            height,
+					array_index:       int64(index),
+					bundle_id:         int64(bundleId),
+				}); err != nil {
+					return err
+				}
+			}
+			for index, endBlockEvents := range kyveItem.Value.BlockResults.EndBlockEvents {
+				if err := sink.Write(TendermintPreProcessedRow{
+					_dlt_raw_id:       "",
+					_dlt_extracted_at: extra.ExtractedAt,
+					block_time:        block.time(),
+					item_type:         "end_block_event",
+					value:             endBlockEvents,
+					height:            height,
+					array_index:       int64(index),
+					bundle_id:         int64(bundleId),
+				}); err != nil {
+					return err
+				}
+			}
+			for index, finalizeBlockEvents := range kyveItem.Value.BlockResults.FinalizeBlockEvents {
+				if err := sink.Write(TendermintPreProcessedRow{
+					_dlt_raw_id:       "",
+					_dlt_extracted_at: extra.ExtractedAt,
+					block_time:        block.time(),
+					item_type:         "finalize_block_event",
+					value:             finalizeBlockEvents,
+					height:            height,
+					array_index:       int64(index),
+					bundle_id:         int64(bundleId),
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return Result{}, err
 	}
 
 	return Result{
-		Data:             columns,
 		CompressedSize:   downloadResult.CompressedSize,
 		UncompressedSize: downloadResult.UncompressedSize,
+		Items:            items,
 	}, nil
 }
@@ -0,0 +1,45 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/KYVENetwork/KYVE-DLT/utils"
+)
+
+func TestTendermintPreProcessedBlockTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+	}{
+		{name: "block", block: `{"header":{"height":"100","time":"2024-01-01T12:00:00Z"}}`},
+		{name: "nested block", block: `{"block_id":{},"block":{"header":{"height":"100","time":"2024-01-01T12:00:00Z"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := `[{"key":"100","value":{"block":` + tt.block + `,"block_results":{"height":"100",` +
+				`"txs_results":[{"code":0}],"end_block_events":[{"type":"transfer"}]}}}]`
+			rows, err := convertItems(t, TendermintPreProcessed{}, items)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows[""]) != 3 {
+				t.Fatalf("got %d rows, want 3", len(rows[""]))
+			}
+			for _, row := range rows[""] {
+				assertValues(t, row, map[string]string{"height": "100", "block_time": "2024-01-01T12:00:00Z"})
+			}
+		})
+	}
+}
+
+func TestInvalidHeight(t *testing.T) {
+	for _, source := range []DataSource{Height{}, TendermintPreProcessed{}} {
+		_, err := convertItems(t, source, `[{"key":"latest","value":{}}]`)
+		var permanentErr *utils.PermanentError
+		if !errors.As(err, &permanentErr) {
+			t.Fatalf("%T: expected a permanent error, got %v", source, err)
+		}
+	}
+}
@@ -1,37 +1,71 @@
 package schema
 
 import (
-	"bytes"
-	"cloud.google.com/go/bigquery"
+	"time"
+
 	"github.com/KYVENetwork/KYVE-DLT/loader/collector"
+	"github.com/KYVENetwork/KYVE-DLT/utils"
 )
 
 type DataSource interface {
-	DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData) (Result, error)
-	GetCSVSchema() []string
-	GetBigQuerySchema() bigquery.Schema
-	GetBigQueryTimePartitioning() *bigquery.TimePartitioning
-	GetBigQueryClustering() *bigquery.Clustering
-	GetPostgresCreateTableCommand(string) string
+	// DownloadAndConvertBundle passes the rows of the bundle to the sink while it is downloaded.
+	// The checksum is verified at the end, so the rows must be discarded if an error is returned.
+	DownloadAndConvertBundle(bundle collector.Bundle, extra ExtraData, sink RowSink) (Result, error)
+	// Tables returns all output tables, the progress is tracked in the first table
+	Tables() []Table
 }
 
 type DataRow interface {
-	ConvertToCSVLine() []string
+	// TableName returns the Name of the table the row is written to
+	TableName() string
+	// Values returns the typed values in the order of the columns of the table
+	Values() []any
+}
+
+// RowSink receives the rows of a bundle one by one, an error stops the conversion.
+type RowSink interface {
+	Write(row DataRow) error
+}
+
+// RowSinkFunc adapts a function to a RowSink.
+type RowSinkFunc func(row DataRow) error
+
+func (f RowSinkFunc) Write(row DataRow) error {
+	return f(row)
 }
 
 type DownloadResult struct {
-	Data             *bytes.Buffer
 	CompressedSize   int64
 	UncompressedSize int64
 }
 
 type Result struct {
-	Data             []DataRow
 	CompressedSize   int64
 	UncompressedSize int64
+	// Items describes all items of the bundle, including the ones outside the key range
+	Items ItemStats
+}
+
+// ItemStats are collected while decoding a bundle to validate it against its metadata.
+type ItemStats struct {
+	Count    int64
+	FirstKey string
+	LastKey  string
+	// Index of the first item whose integer key does not follow the previous key, -1 if all are contiguous
+	GapIndex int64
 }
 
 type ExtraData struct {
 	Name        string
-	ExtractedAt string
+	ExtractedAt time.Time
+	// Archive of offline sources, nil if bundles are downloaded from the storage provider
+	Archive *collector.Archive
+	// Items with keys outside the range are skipped
+	KeyRange collector.KeyRange
+	// OnItem receives the raw items in the key range before they are converted, optional
+	OnItem func(item BaseItem) error
+	// Reserve reserves the memory of the download, decompression and decoding of a bundle before it
+	// is downloaded, given its compressed size or -1 if it is unknown. The reservation grows with the
+	// decompressed data and is released once the bundle was converted, optional
+	Reserve func(compressedSize int64) (*utils.MemoryReservation, error)
 }
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+)
+
+// The values of a row follow the order of the columns of its table and are typed by the column type,
+// nil is NULL:
+//
+//	StringColumn     string
+//	IntegerColumn    int64
+//	BigNumericColumn *big.Int
+//	TimestampColumn  time.Time
+//	JSONColumn       json.RawMessage
+//	BytesColumn      []byte
+//
+// Repeated columns hold a []any with values of the column type. Destinations map the values
+// to their native types, CSVLine serializes them for CSV files.
+
+// CSVLine serializes the values of the row in the format of BigQuery CSV loads.
+func CSVLine(row DataRow) []string {
+	return CSVValues(row.Values())
+}
+
+// CSVValues serializes values which were already taken from a row.
+func CSVValues(values []any) []string {
+	line := make([]string, len(values))
+	for i, value := range values {
+		line[i] = csvValue(value)
+	}
+	return line
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case *big.Int:
+		return v.String()
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case json.RawMessage:
+		return string(v)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case []any:
+		// CSV has no arrays, they are written as JSON array
+		line, _ := json.Marshal(v)
+		return string(line)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// optional returns nil for empty strings, which are NULL.
+func optional(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// repeated returns the values of a repeated column, missing values are an empty array.
+func repeated[T any](values []T) []any {
+	elements := make([]any, len(values))
+	for i, value := range values {
+		elements[i] = value
+	}
+	return elements
+}
+
+// optionalTime returns nil for the zero time, which is NULL.
+func optionalTime(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+	return value
+}
+
+// integer parses an integer, invalid or empty values are NULL.
+func integer(value string) any {
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return i
+}
@@ -119,10 +119,24 @@ func CreateDestinationEntry() yaml.Node {
 				{Kind: yaml.ScalarNode, Value: PromptInput("\033[36mEnter Table ID: \033[0m")},
 				{Kind: yaml.ScalarNode, Value: "bucket_name"},
 				{Kind: yaml.ScalarNode, Value: PromptInput("\033[36mEnter Bucket Name: \033[0m")},
+				{Kind: yaml.ScalarNode, Value: "credentials_file"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter path to service account key (optional): \033[0m", "")},
+				{Kind: yaml.ScalarNode, Value: "impersonate_service_account"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter service account to impersonate (optional): \033[0m", "")},
+				{Kind: yaml.ScalarNode, Value: "big_query_endpoint"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter custom BigQuery endpoint (optional): \033[0m", "")},
+				{Kind: yaml.ScalarNode, Value: "storage_endpoint"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter custom storage endpoint (optional): \033[0m", "")},
 				{Kind: yaml.ScalarNode, Value: "worker_count"},
 				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter Worker count (default 2): \033[0m", "2")},
 				{Kind: yaml.ScalarNode, Value: "bucket_worker_count"},
 				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter Bucket Worker count (default 2): \033[0m", "2")},
+				{Kind: yaml.ScalarNode, Value: "load_job_max_files"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter max files per load job (default 10): \033[0m", "10")},
+				{Kind: yaml.ScalarNode, Value: "load_job_max_size_mb"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter max size per load job in MB (default 4096): \033[0m", "4096")},
+				{Kind: yaml.ScalarNode, Value: "load_job_max_wait"},
+				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter max seconds to wait for a load job (default 60): \033[0m", "60")},
 			},
 		}
 	case "postgres":
@@ -139,8 +153,6 @@ func CreateDestinationEntry() yaml.Node {
 				{Kind: yaml.ScalarNode, Value: PromptInput("\033[36mEnter Table name: \033[0m")},
 				{Kind: yaml.ScalarNode, Value: "worker_count"},
 				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter Worker count (default 4): \033[0m", "4")},
-				{Kind: yaml.ScalarNode, Value: "row_insert_limit"},
-				{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter Row Insert limit (default 500): \033[0m", "500")},
 			},
 		}
 	default:
@@ -148,24 +160,6 @@ func CreateDestinationEntry() yaml.Node {
 	}
 }
 
-func CreateSourceEntry() yaml.Node {
-	return yaml.Node{
-		Kind: yaml.MappingNode,
-		Content: []*yaml.Node{
-			{Kind: yaml.ScalarNode, Value: "name"},
-			{Kind: yaml.ScalarNode, Value: PromptInput("\033[36mEnter Source name: \033[0m")},
-			{Kind: yaml.ScalarNode, Value: "pool_id"},
-			{Kind: yaml.ScalarNode, Value: PromptPoolId("\033[36mEnter KYVE Pool ID: \033[0m")},
-			{Kind: yaml.ScalarNode, Value: "batch_size"},
-			{Kind: yaml.ScalarNode, Value: PromptBatchSize("\033[36mEnter batch size [default 20]: \033[0m", "20")},
-			{Kind: yaml.ScalarNode, Value: "endpoint"},
-			{Kind: yaml.ScalarNode, Value: PromptInputWithDefault("\033[36mEnter endpoint [default https://api.kyve.network]: \033[0m", "https://api.kyve.network")},
-			{Kind: yaml.ScalarNode, Value: "schema"},
-			{Kind: yaml.ScalarNode, Value: PromptSchemaDropdown("\033[36mSelect schema: \033[0m", []string{"base", "height", "tendermint_preprocessed"})},
-		},
-	}
-}
-
 func GetAllConnections(config *Config) (*[]Connection, error) {
 	var connections []Connection
 	for _, connection := range config.Connections {
@@ -220,6 +214,15 @@ func GetConnectionDetails(config *Config, connectionName string) (Source, Destin
 	return source, destination, nil
 }
 
+func GetSourceDetails(config *Config, sourceName string) (Source, error) {
+	for _, src := range config.Sources {
+		if src.Name == sourceName {
+			return src, nil
+		}
+	}
+	return Source{}, fmt.Errorf("source %s not found", sourceName)
+}
+
 func GetConfigPath(configPath string) string {
 	if configPath == "" {
 		home, err := os.UserHomeDir()
@@ -239,6 +242,19 @@ func GetConfigPath(configPath string) string {
 	return configPath
 }
 
+// GetCacheDir returns the configured cache directory or the default one in the dlt home directory.
+func GetCacheDir(config *Config) string {
+	if config.Cache.Dir != "" {
+		return config.Cache.Dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		panic(err)
+	}
+	return filepath.Join(home, ".kyve-dlt", "cache")
+}
+
 func GetNodeValue(node yaml.Node, key string) string {
 	for i := 0; i < len(node.Content); i += 2 {
 		if node.Content[i].Value == key {
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// Until the first bundles were converted, their size is estimated with these defaults.
+const (
+	defaultCompressedBundleSize = 8 * 1024 * 1024
+	defaultExpansionRatio       = 10
+)
+
+// MemoryGovernor limits the memory used by bundles and rows in flight. Workers reserve an
+// estimate of a bundle before they download it and the size of a chunk of rows before they
+// fill it. The destination releases the chunks once it has written the rows.
+// A budget of 0 disables the limit.
+type MemoryGovernor struct {
+	connection string
+	budget     int64
+	semaphore  *semaphore.Weighted
+
+	// Reservations which grew beyond the budget are paid back before memory is
+	// returned to the semaphore, so that later reservations wait for it
+	mu         sync.Mutex
+	overcommit int64
+
+	// Sizes of the converted bundles, used to estimate the next bundles
+	bundles          atomic.Int64
+	compressedSize   atomic.Int64
+	uncompressedSize atomic.Int64
+}
+
+func NewMemoryGovernor(connection string, budget int64) *MemoryGovernor {
+	governor := &MemoryGovernor{
+		connection: connection,
+		budget:     budget,
+	}
+	if budget > 0 {
+		governor.semaphore = semaphore.NewWeighted(budget)
+	}
+
+	PrometheusMemoryBudget.WithLabelValues(connection).Set(float64(budget))
+	return governor
+}
+
+// Reserve blocks until size bytes are available. Reservations larger than the budget
+// are limited to the budget, so that they can still be processed one at a time.
+func (g *MemoryGovernor) Reserve(ctx context.Context, size int64) (*MemoryReservation, error) {
+	size = max(size, 1)
+	if g.semaphore != nil {
+		size = min(size, g.budget)
+
+		PrometheusMemoryWaitingWorkers.WithLabelValues(g.connection).Inc()
+		err := g.semaphore.Acquire(ctx, size)
+		PrometheusMemoryWaitingWorkers.WithLabelValues(g.connection).Dec()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reservation := &MemoryReservation{governor: g}
+	reservation.size.Store(size)
+	PrometheusMemoryReserved.WithLabelValues(g.connection).Add(float64(size))
+	return reservation, nil
+}
+
+// EstimateBundle returns the decompressed size of a bundle from its compressed size and the
+// expansion ratio of the bundles converted so far. If the compressed size is unknown (-1), the
+// average size of the converted bundles is used.
+func (g *MemoryGovernor) EstimateBundle(compressedSize int64) int64 {
+	bundles, compressed, uncompressed := g.bundles.Load(), g.compressedSize.Load(), g.uncompressedSize.Load()
+	if compressedSize < 0 {
+		compressedSize = defaultCompressedBundleSize
+		if bundles > 0 {
+			compressedSize = compressed / bundles
+		}
+	}
+	if compressed <= 0 {
+		return compressedSize * defaultExpansionRatio
+	}
+	return int64(float64(compressedSize) * float64(uncompressed) / float64(compressed))
+}
+
+// ObserveBundle adds the measured sizes of a converted bundle to the estimate.
+func (g *MemoryGovernor) ObserveBundle(compressedSize, uncompressedSize int64) {
+	g.bundles.Add(1)
+	g.compressedSize.Add(compressedSize)
+	g.uncompressedSize.Add(uncompressedSize)
+}
+
+// grow adds size bytes to the reserved memory without waiting. If they are not available,
+// the budget is exceeded until the memory is released again.
+func (g *MemoryGovernor) grow(size int64) {
+	if g.semaphore != nil && !g.semaphore.TryAcquire(size) {
+		g.mu.Lock()
+		g.overcommit += size
+		g.mu.Unlock()
+		PrometheusMemoryOvercommitted.WithLabelValues(g.connection).Add(float64(size))
+	}
+	PrometheusMemoryReserved.WithLabelValues(g.connection).Add(float64(size))
+}
+
+func (g *MemoryGovernor) release(size int64) {
+	if size <= 0 {
+		return
+	}
+	PrometheusMemoryReserved.WithLabelValues(g.connection).Sub(float64(size))
+	if g.semaphore == nil {
+		return
+	}
+
+	g.mu.Lock()
+	paid := min(size, g.overcommit)
+	g.overcommit -= paid
+	g.mu.Unlock()
+	if paid > 0 {
+		PrometheusMemoryOvercommitted.WithLabelValues(g.connection).Sub(float64(paid))
+	}
+	if size > paid {
+		g.semaphore.Release(size - paid)
+	}
+}
+
+type MemoryReservation struct {
+	governor *MemoryGovernor
+	size     atomic.Int64
+}
+
+// Size returns the reserved bytes, 0 once the reservation was released.
+func (r *MemoryReservation) Size() int64 {
+	return r.size.Load()
+}
+
+// Grow extends the reservation to size if it is smaller. It never waits, as waiting for more
+// memory while holding a reservation could deadlock. Memory beyond the budget is counted and
+// later reservations wait until it was released.
+func (r *MemoryReservation) Grow(size int64) {
+	for {
+		current := r.size.Load()
+		// Released reservations stay released
+		if current == 0 || size <= current {
+			return
+		}
+		if r.size.CompareAndSwap(current, size) {
+			r.governor.grow(size - current)
+			return
+		}
+	}
+}
+
+// Shrink releases the part of the reservation which exceeds size.
+func (r *MemoryReservation) Shrink(size int64) {
+	size = max(size, 1)
+	for {
+		current := r.size.Load()
+		if size >= current {
+			return
+		}
+		if r.size.CompareAndSwap(current, size) {
+			r.governor.release(current - size)
+			return
+		}
+	}
+}
+
+// Release frees the whole reservation, it is safe to call it more than once.
+func (r *MemoryReservation) Release() {
+	r.governor.release(r.size.Swap(0))
+}
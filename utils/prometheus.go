@@ -10,6 +10,7 @@ import (
 var (
 	PrometheusSyncStarted         *prometheus.CounterVec
 	PrometheusSyncFinished        *prometheus.CounterVec
+	PrometheusSyncFailed          *prometheus.CounterVec
 	PrometheusBundlesSynced       *prometheus.CounterVec
 	PrometheusSyncStepFailedRetry *prometheus.CounterVec
 
@@ -18,6 +19,14 @@ var (
 
 	PrometheusCompressedBytesSynced   *prometheus.CounterVec
 	PrometheusUncompressedBytesSynced *prometheus.CounterVec
+
+	PrometheusMemoryBudget         *prometheus.GaugeVec
+	PrometheusMemoryReserved       *prometheus.GaugeVec
+	PrometheusMemoryWaitingWorkers *prometheus.GaugeVec
+	PrometheusMemoryOvercommitted  *prometheus.GaugeVec
+
+	PrometheusBundlesUntrusted         *prometheus.CounterVec
+	PrometheusBundleValidationFailures *prometheus.CounterVec
 )
 
 func StartPrometheus(port string) {
@@ -42,6 +51,10 @@ func init() {
 		Name: "sync_finished",
 	}, labelNames)
 
+	PrometheusSyncFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "sync_failed",
+	}, labelNames)
+
 	PrometheusBundlesSynced = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "bundles_synced",
 	}, labelNames)
@@ -65,4 +78,28 @@ func init() {
 	PrometheusUncompressedBytesSynced = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "uncompressed_bytes_synced",
 	}, labelNames)
+
+	PrometheusMemoryBudget = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "memory_budget_bytes",
+	}, labelNames)
+
+	PrometheusMemoryReserved = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "memory_reserved_bytes",
+	}, labelNames)
+
+	PrometheusMemoryWaitingWorkers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "memory_waiting_workers",
+	}, labelNames)
+
+	PrometheusMemoryOvercommitted = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "memory_overcommitted_bytes",
+	}, labelNames)
+
+	PrometheusBundlesUntrusted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bundles_untrusted",
+	}, labelNames)
+
+	PrometheusBundleValidationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "bundle_validation_failures",
+	}, []string{"connection", "check"})
 }
@@ -1,57 +1,17 @@
 package utils
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"github.com/rs/zerolog"
 	"io"
-	"math/rand"
 	"os"
-	"runtime"
 	"time"
 	_ "unsafe"
 )
 
 var GLOBAL_MAX_RAM_GB = uint64(0)
 
-func AwaitEnoughMemory(name string) {
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-
-	logger.Debug().
-		Str("alloc", fmt.Sprintf("%v MiB", bToMb(m.Alloc))).
-		Str("total-alloc", fmt.Sprintf("%v MiB", bToMb(m.TotalAlloc))).
-		Str("sys", fmt.Sprintf("%v MiB", bToMb(m.Sys))).
-		Str("num-gc", fmt.Sprintf("%v", m.NumGC)).
-		Msg("SYSINFO")
-
-	for m.Alloc > GLOBAL_MAX_RAM_GB*1024*1024*1024 {
-		runtime.ReadMemStats(&m)
-
-		logger.Debug().Msg(
-			fmt.Sprintf("SYSINFO: Alloc = %v MiB", bToMb(m.Alloc)) +
-				fmt.Sprintf("\tTotalAlloc = %v MiB", bToMb(m.TotalAlloc)) +
-				fmt.Sprintf("\tSys = %v MiB", bToMb(m.Sys)) +
-				fmt.Sprintf("\tNumGC = %v\n", m.NumGC) +
-				fmt.Sprintf("(%s) Memory limit reached (%d MiB): Waiting 10 seconds ...", name, m.Alloc),
-		)
-
-		logger.Debug().
-			Str("alloc", fmt.Sprintf("%v MiB", bToMb(m.Alloc))).
-			Str("total-alloc", fmt.Sprintf("%v MiB", bToMb(m.TotalAlloc))).
-			Str("sys", fmt.Sprintf("%v MiB", bToMb(m.Sys))).
-			Str("num-gc", fmt.Sprintf("%v", m.NumGC)).
-			Msg(fmt.Sprintf("SYSINFO: (%s) Memory limit reached (%d MiB): Waiting 10 seconds ...", name, m.Alloc))
-
-		runtime.GC()
-		time.Sleep((10 + time.Duration(rand.Intn(10))) * time.Second)
-	}
-
-}
-
-func bToMb(b uint64) uint64 {
-	return b / 1024 / 1024
-}
-
 func DltLogger(moduleName string) zerolog.Logger {
 	writer := io.MultiWriter(os.Stdout)
 	customConsoleWriter := zerolog.ConsoleWriter{Out: writer}
@@ -63,13 +23,49 @@ func DltLogger(moduleName string) zerolog.Logger {
 	return logger
 }
 
-func TryWithExponentialBackoff(try func() error, onError func(error)) {
+// PermanentError stops TryWithExponentialBackoff from retrying.
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Permanent marks an error as not retryable.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// TryWithExponentialBackoff retries until try succeeds. It only
+// returns an error if try returned a PermanentError.
+func TryWithExponentialBackoff(try func() error, onError func(error)) error {
+	return TryWithExponentialBackoffContext(context.Background(), try, onError)
+}
+
+// TryWithExponentialBackoffContext is TryWithExponentialBackoff, which stops waiting
+// for the next try and returns the error of ctx once it is canceled.
+func TryWithExponentialBackoffContext(ctx context.Context, try func() error, onError func(error)) error {
 	importErr := try()
 	var timeout int64 = 1
 	for importErr != nil {
+		var permanentErr *PermanentError
+		if errors.As(importErr, &permanentErr) {
+			return importErr
+		}
+
 		onError(importErr)
-		time.Sleep(time.Second * time.Duration(timeout))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second * time.Duration(timeout)):
+		}
 		importErr = try()
 		timeout *= 2
 	}
+	return nil
 }
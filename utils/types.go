@@ -1,12 +1,14 @@
 package utils
 
 type Config struct {
-	Sources      []Source      `yaml:"sources"`
-	Destinations []Destination `yaml:"destinations"`
-	Connections  []Connection  `yaml:"connections"`
-	Loader       Loader        `yaml:"loader"`
-	LogLevel     string        `yaml:"log_level"`
-	Prometheus   Prometheus    `yaml:"prometheus"`
+	Sources          []Source          `yaml:"sources"`
+	Destinations     []Destination     `yaml:"destinations"`
+	Connections      []Connection      `yaml:"connections"`
+	StorageProviders []StorageProvider `yaml:"storage_providers,omitempty"`
+	Cache            Cache             `yaml:"cache"`
+	Loader           Loader            `yaml:"loader"`
+	LogLevel         string            `yaml:"log_level"`
+	Prometheus       Prometheus        `yaml:"prometheus"`
 }
 
 type Prometheus struct {
@@ -15,25 +17,107 @@ type Prometheus struct {
 }
 
 type Source struct {
-	Name      string `yaml:"name"`
-	PoolID    int    `yaml:"pool_id"`
-	BatchSize int    `yaml:"batch_size"`
-	Endpoint  string `yaml:"endpoint"`
-	Schema    string `yaml:"schema"`
+	Name       string   `yaml:"name"`
+	PoolID     int      `yaml:"pool_id"`
+	BatchSize  int      `yaml:"batch_size"`
+	Endpoint   string   `yaml:"endpoint"`
+	Endpoints  []string `yaml:"endpoints,omitempty"`
+	Path       string   `yaml:"path,omitempty"`
+	Timeout    int      `yaml:"timeout,omitempty"`
+	MaxRetries int      `yaml:"max_retries,omitempty"`
+	Schema     string   `yaml:"schema"`
+	// Adds the storage and finalization metadata of the bundle to every row
+	Provenance bool `yaml:"provenance,omitempty"`
+	// FileDescriptorSets used by the cosmos_txs schema to decode the messages
+	ProtoRegistries []string `yaml:"proto_registries,omitempty"`
+	// Decodes the base64 event attributes of chains before Tendermint 0.35 in the tendermint_events schema,
+	// only below the height if it is set
+	Base64Attributes            bool  `yaml:"base64_attributes,omitempty"`
+	Base64AttributesBelowHeight int64 `yaml:"base64_attributes_below_height,omitempty"`
+
+	TrustPolicy *TrustPolicy `yaml:"trust_policy,omitempty"`
+	Validation  *Validation  `yaml:"validation,omitempty"`
+}
+
+type Validation struct {
+	Mode           string `yaml:"mode,omitempty"`
+	ContiguousKeys bool   `yaml:"contiguous_keys,omitempty"`
+	Summary        string `yaml:"summary,omitempty"`
+}
+
+type TrustPolicy struct {
+	MinVotePowerRatio float64  `yaml:"min_vote_power_ratio,omitempty"`
+	AllowedUploaders  []string `yaml:"allowed_uploaders,omitempty"`
+	DeniedUploaders   []string `yaml:"denied_uploaders,omitempty"`
+	// Seconds since the bundle was finalized
+	MinFinalizationAge int    `yaml:"min_finalization_age,omitempty"`
+	OnViolation        string `yaml:"on_violation,omitempty"`
+	QuarantineFile     string `yaml:"quarantine_file,omitempty"`
+}
+
+// GetEndpoints returns the endpoint together with all additional endpoints.
+func (s Source) GetEndpoints() []string {
+	var endpoints []string
+	if s.Endpoint != "" {
+		endpoints = append(endpoints, s.Endpoint)
+	}
+	for _, e := range s.Endpoints {
+		if !Contains(endpoints, e) {
+			endpoints = append(endpoints, e)
+		}
+	}
+	return endpoints
 }
 
 type Destination struct {
-	Name              string `yaml:"name"`
-	Type              string `yaml:"type"`
-	ProjectID         string `yaml:"project_id,omitempty"`
-	DatasetID         string `yaml:"dataset_id,omitempty"`
-	TableID           string `yaml:"table_id,omitempty"`
-	BucketName        string `yaml:"bucket_name,omitempty"`
-	BucketWorkerCount int    `yaml:"bucket_worker_count,omitempty"`
-	ConnectionURL     string `yaml:"connection_url,omitempty"`
-	TableName         string `yaml:"table_name,omitempty"`
-	RowInsertLimit    int    `yaml:"row_insert_limit,omitempty"`
-	WorkerCount       int    `yaml:"worker_count"`
+	Name              string        `yaml:"name"`
+	Type              string        `yaml:"type"`
+	ProjectID         string        `yaml:"project_id,omitempty"`
+	DatasetID         string        `yaml:"dataset_id,omitempty"`
+	TableID           string        `yaml:"table_id,omitempty"`
+	BucketName        string        `yaml:"bucket_name,omitempty"`
+	CredentialsFile   string        `yaml:"credentials_file,omitempty"`
+	Impersonate       string        `yaml:"impersonate_service_account,omitempty"`
+	BigQueryEndpoint  string        `yaml:"big_query_endpoint,omitempty"`
+	StorageEndpoint   string        `yaml:"storage_endpoint,omitempty"`
+	WithoutAuth       bool          `yaml:"without_authentication,omitempty"`
+	WriteMethod       string        `yaml:"write_method,omitempty"`
+	BucketWorkerCount int           `yaml:"bucket_worker_count,omitempty"`
+	LoadJobMaxFiles   int           `yaml:"load_job_max_files,omitempty"`
+	LoadJobMaxSizeMB  int           `yaml:"load_job_max_size_mb,omitempty"`
+	LoadJobMaxWait    int           `yaml:"load_job_max_wait,omitempty"`
+	Partitioning      *Partitioning `yaml:"partitioning,omitempty"`
+	ConnectionURL     string        `yaml:"connection_url,omitempty"`
+	TableName         string        `yaml:"table_name,omitempty"`
+	WorkerCount       int           `yaml:"worker_count"`
+}
+
+type Partitioning struct {
+	Field         string   `yaml:"field,omitempty"`
+	Type          string   `yaml:"type,omitempty"`
+	RangeStart    int64    `yaml:"range_start,omitempty"`
+	RangeEnd      int64    `yaml:"range_end,omitempty"`
+	RangeInterval int64    `yaml:"range_interval,omitempty"`
+	Clustering    []string `yaml:"clustering,omitempty"`
+	// Tables overrides further tables of the schema by name, the fields above only apply to the first table
+	Tables map[string]Partitioning `yaml:"tables,omitempty"`
+}
+
+type StorageProvider struct {
+	Id       int       `yaml:"id"`
+	Name     string    `yaml:"name"`
+	Gateways []Gateway `yaml:"gateways"`
+}
+
+type Gateway struct {
+	Url     string `yaml:"url"`
+	Timeout int    `yaml:"timeout,omitempty"`
+}
+
+type Cache struct {
+	Enabled   bool   `yaml:"enabled"`
+	Dir       string `yaml:"dir,omitempty"`
+	MaxSizeGB int    `yaml:"max_size_gb"`
 }
 
 type Connection struct {